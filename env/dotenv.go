@@ -0,0 +1,333 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	dotenvMu sync.Mutex
+	// 从文件加载到环境变量的值, 用于区分真实的环境变量
+	dotenvValues = map[string]string{}
+)
+
+// LoadFiles 加载 .env 文件到环境变量, 不存在的文件跳过
+// 优先级 真实环境变量 > 后面的文件 > 前面的文件, 例如 LoadFiles(".env", ".env.local")
+//
+//	# 注释
+//	export HOST=localhost
+//	NAME=test # 行尾注释
+//	PASS='原样 ${不替换}'
+//	DSN="${USER}:${PASS}@tcp(${HOST})"
+//
+// 双引号支持 \n \t 转义和多行, 单引号原样保留, 不带引号和双引号的值替换 ${VAR}
+// 所有文件合并后再替换, 引用的变量以真实环境变量和最后一个文件为准
+func LoadFiles(files ...string) error {
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+
+	values, err := readFiles(files)
+	if err != nil {
+		return err
+	}
+	applyDotenv(values)
+	return nil
+}
+
+// LoadFilesStrict 同 LoadFiles, 文件中有 allowed 之外的变量时返回错误, 不加载任何变量
+// allowed 可以用 Keys 从配置结构体获取
+func LoadFilesStrict(allowed []string, files ...string) error {
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+
+	values, err := readFiles(files)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(allowed))
+	for _, key := range allowed {
+		known[key] = true
+	}
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("env unknown keys: %s", strings.Join(unknown, ", "))
+	}
+
+	applyDotenv(values)
+	return nil
+}
+
+// Keys 结构体 env tag 对应的所有变量名, 包括 envPrefix 嵌套的结构体
+func Keys(v any) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var keys []string
+	collectKeys(t, "", &keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("env")
+		if name == "-" {
+			continue
+		}
+		if name != "" {
+			*keys = append(*keys, prefix+name)
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			collectKeys(ft, prefix+field.Tag.Get("envPrefix"), keys)
+		}
+	}
+}
+
+// dotenvValue 文件中的一个原始值, 合并所有文件后再替换 ${VAR}
+type dotenvValue struct {
+	raw   string
+	quote byte // ' 原样保留, " 处理转义并替换, 0 只替换
+}
+
+// readFiles 按顺序读取, 先合并所有文件的原始值, 后面的文件覆盖前面的, 再替换 ${VAR}
+// 这样 .env 中引用的变量在 .env.local 中覆盖时使用覆盖后的值
+func readFiles(files []string) (map[string]string, error) {
+	// 每个变量按文件顺序的所有定义
+	defs := map[string][]dotenvValue{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := parseDotenv(file, string(data), defs); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &dotenvResolver{defs: defs, cache: map[string]string{}, active: map[string]int{}}
+	// 按名称顺序替换, 循环引用时结果固定
+	keys := make([]string, 0, len(defs))
+	for key := range defs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make(map[string]string, len(defs))
+	for _, key := range keys {
+		values[key] = r.resolveAt(key, len(defs[key])-1)
+	}
+	return values, nil
+}
+
+// dotenvResolver 替换 ${VAR}, 优先使用真实环境变量, 然后是合并后的值
+// 引用自己时使用前一个定义, 例如 .env.local 中 PATH_EXTRA="${PATH_EXTRA}:/opt", 没有前一个定义时为空
+type dotenvResolver struct {
+	defs   map[string][]dotenvValue
+	cache  map[string]string
+	active map[string]int // 正在替换的变量和定义的位置
+}
+
+func (r *dotenvResolver) resolve(key string) string {
+	if v, ok := realEnv(key); ok {
+		return v
+	}
+	idx := len(r.defs[key]) - 1
+	if i, ok := r.active[key]; ok {
+		idx = i - 1
+	}
+	return r.resolveAt(key, idx)
+}
+
+func (r *dotenvResolver) resolveAt(key string, idx int) string {
+	if idx < 0 {
+		return ""
+	}
+	cacheKey := fmt.Sprintf("%s#%d", key, idx)
+	if v, ok := r.cache[cacheKey]; ok {
+		return v
+	}
+
+	prev, wasActive := r.active[key]
+	r.active[key] = idx
+	def := r.defs[key][idx]
+	var value string
+	switch def.quote {
+	case '\'':
+		value = def.raw
+	case '"':
+		value = expand(def.raw, true, r.resolve)
+	default:
+		value = expand(def.raw, false, r.resolve)
+	}
+	if wasActive {
+		r.active[key] = prev
+	} else {
+		delete(r.active, key)
+	}
+
+	r.cache[cacheKey] = value
+	return value
+}
+
+// applyDotenv 写入环境变量, 跳过真实环境变量已经设置的
+func applyDotenv(values map[string]string) {
+	for key, value := range values {
+		if _, ok := realEnv(key); ok {
+			continue
+		}
+		_ = os.Setenv(key, value)
+		dotenvValues[key] = value
+	}
+}
+
+// realEnv 真实的环境变量, 不包括之前从文件加载的
+func realEnv(key string) (string, bool) {
+	value, ok := lookup(key)
+	if !ok {
+		return "", false
+	}
+	if v, fromFile := dotenvValues[key]; fromFile && v == value {
+		return "", false
+	}
+	return value, true
+}
+
+func parseDotenv(name, content string, defs map[string][]dotenvValue) error {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, rest, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validKey(key) {
+			return fmt.Errorf("%s:%d: invalid line %q", name, lineNo, lines[i])
+		}
+		rest = strings.TrimSpace(rest)
+
+		var value dotenvValue
+		switch {
+		case strings.HasPrefix(rest, "'"):
+			end := strings.IndexByte(rest[1:], '\'')
+			if end < 0 {
+				return fmt.Errorf("%s:%d: unterminated single quote", name, lineNo)
+			}
+			value = dotenvValue{raw: rest[1 : 1+end], quote: '\''}
+		case strings.HasPrefix(rest, `"`):
+			body := rest[1:]
+			for {
+				if end := closingQuote(body); end >= 0 {
+					body = body[:end]
+					break
+				}
+				// 多行的值
+				i++
+				if i >= len(lines) {
+					return fmt.Errorf("%s:%d: unterminated double quote", name, lineNo)
+				}
+				body += "\n" + lines[i]
+			}
+			value = dotenvValue{raw: body, quote: '"'}
+		default:
+			if idx := strings.Index(rest, " #"); idx >= 0 {
+				rest = strings.TrimSpace(rest[:idx])
+			}
+			if idx := strings.Index(rest, "\t#"); idx >= 0 {
+				rest = strings.TrimSpace(rest[:idx])
+			}
+			value = dotenvValue{raw: rest}
+		}
+		defs[key] = append(defs[key], value)
+	}
+	return nil
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		if c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// closingQuote 没有转义的双引号位置
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// expand 替换 ${VAR}, escapes 为 true 时处理反斜杠转义
+func expand(s string, escapes bool, resolve func(string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if escapes && c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		if c == '$' && i+1 < len(s) && s[i+1] == '{' {
+			if end := strings.IndexByte(s[i+2:], '}'); end >= 0 {
+				b.WriteString(resolve(s[i+2 : i+2+end]))
+				i += 2 + end
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
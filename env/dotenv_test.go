@@ -0,0 +1,99 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	file := filepath.Join(dir, name)
+	assert.Nil(t, os.WriteFile(file, []byte(content), 0o644))
+	return file
+}
+
+// clearEnv 测试结束后恢复环境变量
+func clearEnv(t *testing.T, keys ...string) {
+	for _, key := range keys {
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+	clearEnv(t, "DOT_HOST", "DOT_USER", "DOT_PASS", "DOT_DSN", "DOT_NOTE", "DOT_RAW", "DOT_MULTI", "DOT_REAL")
+	t.Setenv("DOT_REAL", "from-env")
+
+	dir := t.TempDir()
+	base := writeFile(t, dir, ".env", `
+# 注释
+export DOT_HOST=localhost
+DOT_USER = root # 行尾注释
+DOT_PASS='p#${x}'
+DOT_DSN="${DOT_USER}:${DOT_PASS}@${DOT_HOST}"
+DOT_NOTE="a \"quoted\"\tb"
+DOT_MULTI="line1
+line2"
+DOT_REAL=from-file
+`)
+	local := writeFile(t, dir, ".env.local", "DOT_HOST=10.0.0.1\nDOT_USER=\"${DOT_USER}-local\"\n")
+
+	assert.Nil(t, LoadFiles(base, local, filepath.Join(dir, "missing")))
+	assert.Equal(t, "10.0.0.1", os.Getenv("DOT_HOST"))
+	// 引用自己时使用前一个文件的值
+	assert.Equal(t, "root-local", os.Getenv("DOT_USER"))
+	assert.Equal(t, "p#${x}", os.Getenv("DOT_PASS"))
+	// .env 中的引用使用 .env.local 覆盖后的值
+	assert.Equal(t, "root-local:p#${x}@10.0.0.1", os.Getenv("DOT_DSN"))
+	assert.Equal(t, "a \"quoted\"\tb", os.Getenv("DOT_NOTE"))
+	assert.Equal(t, "line1\nline2", os.Getenv("DOT_MULTI"))
+	assert.Equal(t, "from-env", os.Getenv("DOT_REAL"))
+
+	// 再次加载可以覆盖之前从文件加载的值
+	writeFile(t, dir, ".env.local", "DOT_HOST=10.0.0.2\n")
+	assert.Nil(t, LoadFiles(base, local))
+	assert.Equal(t, "10.0.0.2", os.Getenv("DOT_HOST"))
+	assert.Equal(t, "root:p#${x}@10.0.0.2", os.Getenv("DOT_DSN"))
+}
+
+func TestLoadFilesError(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, ".env", "OK=1\nnot a line\n")
+	err := LoadFiles(file)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), ".env:2")
+
+	file = writeFile(t, dir, ".env", "A=\"open\n")
+	assert.NotNil(t, LoadFiles(file))
+}
+
+func TestLoadFilesStrict(t *testing.T) {
+	clearEnv(t, "DB_HOST", "DB_PASS")
+
+	keys := Keys(&dbCfg{})
+	assert.Equal(t, []string{"HOST", "PORT", "NAME", "PASS"}, keys)
+	assert.Equal(t, []string{"DEBUG", "TIMEOUT", "HOSTS", "MYSQL_READ_HOST", "MYSQL_READ_PORT", "MYSQL_READ_NAME",
+		"MYSQL_READ_PASS", "MYSQL_WRITE_HOST", "MYSQL_WRITE_PORT", "MYSQL_WRITE_NAME", "MYSQL_WRITE_PASS"}, Keys(appCfg{}))
+
+	dir := t.TempDir()
+	file := writeFile(t, dir, ".env", "DB_HOST=h\nDB_PASS=p\nDB_TYPO=x\n")
+	err := LoadFilesStrict([]string{"DB_HOST", "DB_PASS"}, file)
+	assert.EqualError(t, err, "env unknown keys: DB_TYPO")
+	assert.Equal(t, "", os.Getenv("DB_HOST"))
+
+	writeFile(t, dir, ".env", "DB_HOST=h\nDB_PASS=p\n")
+	assert.Nil(t, LoadFilesStrict([]string{"DB_HOST", "DB_PASS"}, file))
+	assert.Equal(t, "h", os.Getenv("DB_HOST"))
+}
+
+// 循环引用不会死循环, 引用链上已经在替换的变量为空
+func TestLoadFilesCycle(t *testing.T) {
+	clearEnv(t, "DOT_A", "DOT_B")
+
+	dir := t.TempDir()
+	file := writeFile(t, dir, ".env", "DOT_A=a${DOT_B}\nDOT_B=b${DOT_A}\n")
+	assert.Nil(t, LoadFiles(file))
+	assert.Equal(t, "ab", os.Getenv("DOT_A"))
+	assert.Equal(t, "b", os.Getenv("DOT_B"))
+}
@@ -0,0 +1,107 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 变量来源
+const (
+	SourceEnv     = "env"
+	SourceDotenv  = "dotenv"
+	SourceFile    = "file"
+	SourceDefault = "default"
+	SourceUnset   = "unset"
+)
+
+// 名称包含这些词的变量 Dump 时隐藏值
+var sensitiveWords = []string{"PASS", "KEY", "SECRET", "TOKEN"}
+
+type readRecord struct {
+	source string
+	value  string
+}
+
+var (
+	readMu  sync.Mutex
+	readLog = map[string]readRecord{}
+)
+
+// read 读取变量并记录来源
+// FOO 未设置时读取 FOO_FILE 指向的文件内容, 用于 kubernetes 挂载的密码文件
+func read(key string) (string, bool, error) {
+	if value, ok := lookup(key); ok {
+		record(key, envSource(key, value), value)
+		return value, true, nil
+	}
+
+	if file, ok := lookup(key + "_FILE"); ok {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("env %s_FILE: %w", key, err)
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		record(key, SourceFile+":"+file, value)
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// envSource 区分真实环境变量和 .env 文件加载的
+func envSource(key, value string) string {
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+	if v, ok := dotenvValues[key]; ok && v == value {
+		return SourceDotenv
+	}
+	return SourceEnv
+}
+
+func record(key, source, value string) {
+	readMu.Lock()
+	defer readMu.Unlock()
+	readLog[key] = readRecord{source: source, value: value}
+}
+
+// Dump 列出读取过的所有变量和来源, 密码等敏感变量隐藏值, 启动时打印用于排查配置问题
+//
+//	MYSQL_HOST=10.0.0.1 (env)
+//	MYSQL_PASS=****** (file:/run/secrets/mysql_pass)
+//	MYSQL_PORT=3306 (default)
+func Dump() string {
+	readMu.Lock()
+	keys := make([]string, 0, len(readLog))
+	for key := range readLog {
+		keys = append(keys, key)
+	}
+	records := make(map[string]readRecord, len(readLog))
+	for key, r := range readLog {
+		records[key] = r
+	}
+	readMu.Unlock()
+
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		r := records[key]
+		value := r.value
+		if value != "" && sensitive(key) {
+			value = "******"
+		}
+		fmt.Fprintf(&b, "%s=%s (%s)\n", key, value, r.source)
+	}
+	return b.String()
+}
+
+func sensitive(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, word := range sensitiveWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package env
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSecret(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "pass", "s3cret\n")
+	t.Setenv("SEC_PASS_FILE", file)
+	t.Setenv("SEC_TOKEN", "direct")
+	t.Setenv("SEC_TOKEN_FILE", file)
+
+	assert.Equal(t, "s3cret", Get("SEC_PASS", ""))
+	// FOO 优先于 FOO_FILE
+	assert.Equal(t, "direct", Get("SEC_TOKEN", ""))
+
+	t.Setenv("SEC_BAD_FILE", filepath.Join(dir, "missing"))
+	_, err := GetE("SEC_BAD", "")
+	assert.NotNil(t, err)
+
+	t.Setenv("DB_PASS_FILE", file)
+	var cfg dbCfg
+	assert.Nil(t, LoadPrefix(&cfg, "DB_"))
+	assert.Equal(t, "s3cret", cfg.Pass)
+}
+
+func TestDump(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DUMP_HOST", "10.0.0.1")
+	t.Setenv("DUMP_SECRET_FILE", writeFile(t, dir, "secret", "xyz"))
+	clearEnv(t, "DUMP_NAME")
+	assert.Nil(t, LoadFiles(writeFile(t, dir, ".env", "DUMP_NAME=test\n")))
+
+	Get("DUMP_HOST", "")
+	Get("DUMP_PORT", 3306)
+	Get("DUMP_SECRET", "")
+	Get("DUMP_NAME", "")
+
+	dump := Dump()
+	assert.Contains(t, dump, "DUMP_HOST=10.0.0.1 (env)\n")
+	assert.Contains(t, dump, "DUMP_PORT=3306 (default)\n")
+	assert.Contains(t, dump, "DUMP_SECRET=****** (file:"+filepath.Join(dir, "secret")+")\n")
+	assert.Contains(t, dump, "DUMP_NAME=test (dotenv)\n")
+	assert.NotContains(t, dump, "xyz")
+}
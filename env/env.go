@@ -4,40 +4,64 @@ import (
 	"fmt"
 	"os"
 	"reflect"
-	"strconv"
+
+	"github.com/zeromicro/go-zero/core/logx"
 )
 
-// Get 获取环境变量
+// Get 获取环境变量, 未设置或者解析失败时返回默认值, 解析失败会打错误日志
+// 支持的类型见 GetE
 func Get[T any](key string, defaultValue T) T {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	value, err := GetE(key, defaultValue)
+	if err != nil {
+		logx.Errorf("读取环境变量失败 %s", err.Error())
+	}
+	return value
+}
+
+// GetE 获取环境变量, 未设置时返回默认值, 解析失败返回错误
+// FOO 未设置时读取 FOO_FILE 指向的文件内容
+// 支持 string bool int* uint* float* time.Duration time.Time url.URL encoding.TextUnmarshaler
+// 切片用逗号分隔 "a,b,c", map 格式 "k1=v1,k2=v2"
+func GetE[T any](key string, defaultValue T) (T, error) {
+	raw, ok, err := read(key)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		record(key, SourceDefault, fmt.Sprint(defaultValue))
+		return defaultValue, nil
+	}
+	return parse(key, raw, defaultValue)
+}
+
+// MustGet 获取必须设置的环境变量, 未设置或者解析失败 panic
+func MustGet[T any](key string) T {
+	var zero T
+	raw, ok, err := read(key)
+	if err != nil {
+		panic(err.Error())
+	}
+	if !ok {
+		record(key, SourceUnset, "")
+		panic(fmt.Sprintf("env %s is required", key))
+	}
+	value, err := parse(key, raw, zero)
+	if err != nil {
+		panic(err.Error())
 	}
+	return value
+}
 
+func parse[T any](key, raw string, defaultValue T) (T, error) {
 	var result T
-	switch any(result).(type) {
-	case string:
-		return any(value).(T)
-	case int:
-		if v, err := strconv.Atoi(value); err == nil {
-			return any(v).(T)
-		}
-	case int64:
-		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
-			return any(v).(T)
-		}
-	case float64:
-		if v, err := strconv.ParseFloat(value, 64); err == nil {
-			return any(v).(T)
-		}
-	case bool:
-		if v, err := strconv.ParseBool(value); err == nil {
-			return any(v).(T)
-		}
-	default:
-		panic(fmt.Sprintf("env unsupported type: %v", reflect.TypeOf(defaultValue)))
+	if err := setValue(reflect.ValueOf(&result).Elem(), raw); err != nil {
+		return defaultValue, fmt.Errorf("env %s=%q: %w", key, raw, err)
 	}
+	return result, nil
+}
 
-	// 转换失败，返回默认值
-	return defaultValue
+// lookup 读取环境变量, 空值视为未设置
+func lookup(key string) (string, bool) {
+	value := os.Getenv(key)
+	return value, value != ""
 }
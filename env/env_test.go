@@ -0,0 +1,66 @@
+package env
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	t.Setenv("T_STR", "abc")
+	t.Setenv("T_INT", "12")
+	t.Setenv("T_UINT", "7")
+	t.Setenv("T_INT32", "-3")
+	t.Setenv("T_BOOL", "true")
+	t.Setenv("T_DUR", "1m30s")
+	t.Setenv("T_LIST", "a, b ,c")
+	t.Setenv("T_MAP", "a=1, b=2")
+	t.Setenv("T_TIME", "2024-05-01 08:00:00")
+	t.Setenv("T_URL", "https://example.com/path?q=1")
+	t.Setenv("T_IP", "10.0.0.1")
+
+	assert.Equal(t, "abc", Get("T_STR", ""))
+	assert.Equal(t, 12, Get("T_INT", 0))
+	assert.Equal(t, uint16(7), Get("T_UINT", uint16(0)))
+	assert.Equal(t, int32(-3), Get("T_INT32", int32(0)))
+	assert.True(t, Get("T_BOOL", false))
+	assert.Equal(t, 90*time.Second, Get("T_DUR", time.Duration(0)))
+	assert.Equal(t, []string{"a", "b", "c"}, Get[[]string]("T_LIST", nil))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, Get[map[string]string]("T_MAP", nil))
+	assert.Equal(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local), Get("T_TIME", time.Time{}))
+
+	u := Get("T_URL", url.URL{})
+	assert.Equal(t, "example.com", u.Host)
+	assert.Equal(t, "1", u.Query().Get("q"))
+	assert.Equal(t, "10.0.0.1", Get("T_IP", net.IP{}).String())
+
+	assert.Equal(t, "def", Get("T_MISSING", "def"))
+}
+
+func TestGetE(t *testing.T) {
+	t.Setenv("T_PORT", "80a")
+
+	port, err := GetE("T_PORT", 3306)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3306, port)
+	assert.Equal(t, 3306, Get("T_PORT", 3306))
+
+	_, err = GetE("T_PORT", struct{}{})
+	assert.NotNil(t, err)
+
+	port, err = GetE("T_MISSING", 3306)
+	assert.Nil(t, err)
+	assert.Equal(t, 3306, port)
+}
+
+func TestMustGet(t *testing.T) {
+	t.Setenv("T_PORT", "80")
+	t.Setenv("T_BAD", "80a")
+
+	assert.Equal(t, 80, MustGet[int]("T_PORT"))
+	assert.Panics(t, func() { MustGet[int]("T_BAD") })
+	assert.Panics(t, func() { MustGet[string]("T_MISSING") })
+}
@@ -0,0 +1,94 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Load 按 tag 从环境变量填充结构体, v 必须是结构体指针
+//
+//	type Cfg struct {
+//		Host  string     `env:"HOST" default:"localhost"`
+//		Pass  string     `env:"PASS" required:"true"`
+//		Read  mysql.Cfg  `envPrefix:"MYSQL_READ_"`
+//		Redis wredis.Cfg `envPrefix:"REDIS_"`
+//	}
+//
+// 没有 env tag 的结构体字段按 envPrefix 加前缀后递归填充
+// 环境变量未设置时, 字段是零值才使用 default, 已经有值的字段保留原值
+// FOO 未设置时读取 FOO_FILE 指向的文件内容
+// 所有缺少和解析失败的变量合并成一个错误返回
+func Load(v any) error {
+	return LoadPrefix(v, "")
+}
+
+// LoadPrefix 同 Load, 所有变量名加上 prefix, 例如 LoadPrefix(&cfg, "MYSQL_READ_")
+func LoadPrefix(v any, prefix string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env load: need a struct pointer, got %T", v)
+	}
+
+	var errs []error
+	loadStruct(rv.Elem(), prefix, &errs)
+	return errors.Join(errs...)
+}
+
+func loadStruct(rv reflect.Value, prefix string, errs *[]error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		name := field.Tag.Get("env")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if nested, ok := nestedStruct(fv); ok {
+				loadStruct(nested, prefix+field.Tag.Get("envPrefix"), errs)
+			}
+			continue
+		}
+
+		key := prefix + name
+		raw, ok, err := read(key)
+		if err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+		if !ok {
+			if field.Tag.Get("required") == "true" {
+				record(key, SourceUnset, "")
+				*errs = append(*errs, fmt.Errorf("env %s is required", key))
+				continue
+			}
+			def, hasDefault := field.Tag.Lookup("default")
+			if !hasDefault || !fv.IsZero() {
+				record(key, SourceDefault, fmt.Sprint(fv.Interface()))
+				continue
+			}
+			raw = def
+			record(key, SourceDefault, raw)
+		}
+
+		if err := setValue(fv, raw); err != nil {
+			*errs = append(*errs, fmt.Errorf("env %s=%q: %w", key, raw, err))
+		}
+	}
+}
+
+// nestedStruct 结构体或者非 nil 的结构体指针
+func nestedStruct(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
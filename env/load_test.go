@@ -0,0 +1,64 @@
+package env
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dbCfg struct {
+	Host string `env:"HOST" default:"localhost"`
+	Port int    `env:"PORT" default:"3306"`
+	Name string `env:"NAME" default:"db_name"`
+	Pass string `env:"PASS" required:"true"`
+}
+
+type appCfg struct {
+	Debug   bool          `env:"DEBUG"`
+	Timeout time.Duration `env:"TIMEOUT" default:"3s"`
+	Hosts   []string      `env:"HOSTS"`
+	Skip    string        `env:"-"`
+	Read    dbCfg         `envPrefix:"MYSQL_READ_"`
+	Write   *dbCfg        `envPrefix:"MYSQL_WRITE_"`
+	private string
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("DEBUG", "true")
+	t.Setenv("HOSTS", "a:1, b:2,")
+	t.Setenv("MYSQL_READ_HOST", "10.0.0.1")
+	t.Setenv("MYSQL_READ_PASS", "p1")
+	t.Setenv("MYSQL_WRITE_PASS", "p2")
+	t.Setenv("MYSQL_WRITE_PORT", "3307")
+
+	cfg := appCfg{Write: &dbCfg{Name: "test"}}
+	assert.Nil(t, Load(&cfg))
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, 3*time.Second, cfg.Timeout)
+	assert.Equal(t, []string{"a:1", "b:2"}, cfg.Hosts)
+	assert.Equal(t, dbCfg{Host: "10.0.0.1", Port: 3306, Name: "db_name", Pass: "p1"}, cfg.Read)
+	// 已有的值不被默认值覆盖
+	assert.Equal(t, dbCfg{Host: "localhost", Port: 3307, Name: "test", Pass: "p2"}, *cfg.Write)
+}
+
+func TestLoadPrefix(t *testing.T) {
+	t.Setenv("DB_PASS", "secret")
+	var cfg dbCfg
+	assert.Nil(t, LoadPrefix(&cfg, "DB_"))
+	assert.Equal(t, "secret", cfg.Pass)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("MYSQL_READ_PORT", "80a")
+	t.Setenv("TIMEOUT", "3")
+
+	var cfg appCfg
+	err := Load(&cfg)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `env TIMEOUT="3"`)
+	assert.Contains(t, err.Error(), `env MYSQL_READ_PORT="80a"`)
+	assert.Contains(t, err.Error(), "env MYSQL_READ_PASS is required")
+
+	assert.NotNil(t, Load(cfg))
+}
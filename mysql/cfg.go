@@ -1,13 +1,14 @@
 package mysql
 
 // Cfg 数据库配置
+// 可以用 env.LoadPrefix(&cfg, "MYSQL_READ_") 从环境变量读取
 type Cfg struct {
-	User    string `json:",default=root,optional"`
-	Pass    string `json:",default=root,optional"`
-	Host    string `json:",default=localhost,optional"`
-	Port    int    `json:",default=3306,optional"`
-	Name    string `json:",default=db_name,optional"`
-	Zone    string `json:",default=+08:00,optional"`        // 格式 "+08:00" mysql 连接使用的
-	TimeLoc string `json:",default=Asia/Shanghai,optional"` // 格式 Asia/Shanghai  mysql日期格式转成  time.Time使用的的时区
-	Charset string `json:",default=utf8mb4,optional"`
+	User    string `json:",default=root,optional" env:"USER" default:"root"`
+	Pass    string `json:",default=root,optional" env:"PASS" default:"root"`
+	Host    string `json:",default=localhost,optional" env:"HOST" default:"localhost"`
+	Port    int    `json:",default=3306,optional" env:"PORT" default:"3306"`
+	Name    string `json:",default=db_name,optional" env:"NAME" default:"db_name"`
+	Zone    string `json:",default=+08:00,optional" env:"ZONE" default:"+08:00"`                   // 格式 "+08:00" mysql 连接使用的
+	TimeLoc string `json:",default=Asia/Shanghai,optional" env:"TIME_LOC" default:"Asia/Shanghai"` // 格式 Asia/Shanghai  mysql日期格式转成  time.Time使用的的时区
+	Charset string `json:",default=utf8mb4,optional" env:"CHAR" default:"utf8mb4"`
 }
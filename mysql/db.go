@@ -11,6 +11,7 @@ import (
 
 type Db struct {
 	conn sqlx.SqlConn
+	sess sqlx.Session // 执行SQL使用, 事务中是事务的 session
 }
 
 func (s *Db) isMap(data any) bool {
@@ -194,7 +195,7 @@ func (s *Db) updateStruct(tableName string, data any, conditions map[string]any)
 	// 构建 SQL 语句
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableName, setClause, whereClause)
 
-	result, err := s.conn.Exec(query, args...)
+	result, err := s.sess.Exec(query, args...)
 
 	if err != nil {
 		return 0, err
@@ -226,7 +227,7 @@ func (s *Db) updateMap(tableName string, data map[string]any, conditions map[str
 	// 构建 SQL 语句
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableName, setClause, whereClause)
 
-	result, err := s.conn.Exec(query, args...)
+	result, err := s.sess.Exec(query, args...)
 
 	if err != nil {
 		return 0, err
@@ -254,7 +255,7 @@ func (s *Db) Delete(tableName string, conditions map[string]any) (int64, error)
 	query := fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, whereClause)
 
 	// 设置参数并执行更新
-	result, err := s.conn.Exec(query, args...)
+	result, err := s.sess.Exec(query, args...)
 
 	if err != nil {
 		return 0, err
@@ -264,14 +265,14 @@ func (s *Db) Delete(tableName string, conditions map[string]any) (int64, error)
 }
 
 func (s *Db) GetRow(v any, query string, args ...any) error {
-	return s.conn.QueryRow(v, query, args...)
+	return s.sess.QueryRow(v, query, args...)
 }
 
 func (s *Db) GetData(v any, query string, args ...any) error {
-	return s.conn.QueryRows(v, query, args...)
+	return s.sess.QueryRows(v, query, args...)
 }
 func (s *Db) Exec(query string, args ...any) (sql.Result, error) {
-	result, err := s.conn.Exec(query, args...)
+	result, err := s.sess.Exec(query, args...)
 	if err == nil {
 		return result, nil
 	} else {
@@ -279,6 +280,47 @@ func (s *Db) Exec(query string, args ...any) (sql.Result, error) {
 	}
 }
 
+// GetRowNamed 使用命名参数查询一行
+// 例如 GetRowNamed(&v, "SELECT * FROM user WHERE id = :id", map[string]any{"id": 1})
+// arg 是 map[string]any 或 struct, 切片参数自动展开 IN (:ids)
+func (s *Db) GetRowNamed(v any, query string, arg any) error {
+	q, args, err := bindNamed(query, arg)
+	if err != nil {
+		return err
+	}
+	return s.GetRow(v, q, args...)
+}
+
+// GetDataNamed 使用命名参数查询多行
+func (s *Db) GetDataNamed(v any, query string, arg any) error {
+	q, args, err := bindNamed(query, arg)
+	if err != nil {
+		return err
+	}
+	return s.GetData(v, q, args...)
+}
+
+// ExecNamed 使用命名参数执行SQL
+func (s *Db) ExecNamed(query string, arg any) (sql.Result, error) {
+	q, args, err := bindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Exec(q, args...)
+}
+
+// Transact 在事务中执行 fn, fn 返回错误时回滚
+// fn 里面必须使用 tx 执行SQL, 不支持嵌套事务
+func (s *Db) Transact(fn func(tx *Db) error) error {
+	if s.conn == nil {
+		return fmt.Errorf("不支持嵌套事务")
+	}
+	return s.conn.Transact(func(session sqlx.Session) error {
+		return fn(&Db{sess: session})
+	})
+}
+
+// GetConn 获取原始连接, 事务中返回 nil
 func (s *Db) GetConn() sqlx.SqlConn {
 	return s.conn
 }
@@ -27,32 +27,24 @@ CREATE TABLE `test` (
 
 func TestDb(t *testing.T) {
 
+	// 本地测试可以把 MYSQL_* 写在 mysql/.env.local
+	assert.Nil(t, env.LoadFiles(".env", ".env.local"))
+
+	if env.Get("MYSQL_WRITE_HOST", "") == "" {
+		t.Skip("MYSQL_WRITE_HOST 未设置, 跳过数据库测试")
+	}
+
 	if env.Get("MYSQL_READ_HOST", "") != "" {
-		rc := Cfg{
-			Host:    env.Get("MYSQL_READ_HOST", ""),
-			Port:    env.Get("MYSQL_READ_PORT", 3306),
-			User:    env.Get("MYSQL_READ_USER", "root"),
-			Pass:    env.Get("MYSQL_READ_PASS", "root"),
-			Name:    env.Get("MYSQL_READ_NAME", "test"),
-			Zone:    env.Get("MYSQL_READ_ZONE", "+08:00"),
-			Charset: env.Get("MYSQL_READ_CHAR", "utf8mb4"),
-		}
+		rc := Cfg{Name: "test"}
+		assert.Nil(t, env.LoadPrefix(&rc, "MYSQL_READ_"))
 		Init("read", rc)
 	}
-	if env.Get("MYSQL_WRITE_HOST", "") != "" {
-		wc := Cfg{
-			Host:    env.Get("MYSQL_WRITE_HOST", ""),
-			Port:    env.Get("MYSQL_READ_PORT", 3306),
-			User:    env.Get("MYSQL_WRITE_USER", "root"),
-			Pass:    env.Get("MYSQL_WRITE_PASS", "root"),
-			Name:    env.Get("MYSQL_WRITE_NAME", "test"),
-			Zone:    env.Get("MYSQL_WRITE_ZONE", "+08:00"),
-			Charset: env.Get("MYSQL_WRITE_CHAR", "utf8mb4"),
-		}
-		Init("write", wc)
-	}
 
-	db := GetClient("write")
+	wc := Cfg{Name: "test"}
+	assert.Nil(t, env.LoadPrefix(&wc, "MYSQL_WRITE_"))
+	Init("write", wc)
+
+	db := Get("write")
 
 	type row struct {
 		Name  string  `db:"name"`
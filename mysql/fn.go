@@ -27,7 +27,8 @@ func Init(name string, cfg Cfg) {
 		cfg.Charset,
 		url.QueryEscape(fmt.Sprintf("'%s'", cfg.Zone)),
 	)
-	instance.Store(name, &Db{conn: sqlx.NewMysql(dsn)})
+	conn := sqlx.NewMysql(dsn)
+	instance.Store(name, &Db{conn: conn, sess: conn})
 
 }
 
@@ -38,3 +39,8 @@ func Get(name string) *Db {
 	}
 	return conn.(*Db)
 }
+
+// NewDb 使用已有的连接创建, 例如 sqlx.NewSqlConnFromDB 包装的 *sql.DB
+func NewDb(conn sqlx.SqlConn) *Db {
+	return &Db{conn: conn, sess: conn}
+}
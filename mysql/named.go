@@ -0,0 +1,156 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// bindNamed 把命名参数 (:user_id) 转换成 ? 占位符
+// arg 支持 map[string]any 和 struct (struct 按 db tag 取名, 没有 tag 使用字段名)
+// 参数是切片时自动展开, 用于 IN (:ids)
+func bindNamed(query string, arg any) (string, []any, error) {
+	params, err := namedParams(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf strings.Builder
+	var args []any
+	var quote rune
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		// 引号内的内容原样输出
+		if quote != 0 {
+			buf.WriteRune(c)
+			if c == '\\' && quote != '`' && i+1 < len(runes) {
+				i++
+				buf.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			buf.WriteRune(c)
+		case c == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			// :: 转义为 :
+			buf.WriteRune(':')
+			i++
+		case c == ':' && i+1 < len(runes) && isNameStart(runes[i+1]):
+			j := i + 1
+			for j < len(runes) && isNamePart(runes[j]) {
+				j++
+			}
+			name := string(runes[i+1 : j])
+			value, ok := params[name]
+			if !ok {
+				return "", nil, fmt.Errorf("命名参数 :%s 没有对应的值 sql:%s", name, query)
+			}
+			placeholder, values, err := expandArg(name, value)
+			if err != nil {
+				return "", nil, err
+			}
+			buf.WriteString(placeholder)
+			args = append(args, values...)
+			i = j - 1
+		default:
+			buf.WriteRune(c)
+		}
+	}
+
+	return buf.String(), args, nil
+}
+
+// expandArg 切片参数展开成 ?,?,?
+func expandArg(name string, value any) (string, []any, error) {
+	if value == nil {
+		return "?", []any{nil}, nil
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return "?", []any{value}, nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "?", []any{value}, nil
+	}
+	// []byte 是一个值不是列表
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return "?", []any{value}, nil
+	}
+	if v.Len() == 0 {
+		return "", nil, fmt.Errorf("命名参数 :%s 是空切片", name)
+	}
+
+	placeholders := make([]string, v.Len())
+	values := make([]any, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		placeholders[i] = "?"
+		values[i] = v.Index(i).Interface()
+	}
+	return strings.Join(placeholders, ","), values, nil
+}
+
+// namedParams 把参数转成 名称 => 值
+func namedParams(arg any) (map[string]any, error) {
+	if m, ok := arg.(map[string]any); ok {
+		return m, nil
+	}
+
+	valueOf := reflect.ValueOf(arg)
+	if valueOf.Kind() == reflect.Ptr {
+		if valueOf.IsNil() {
+			return nil, fmt.Errorf("named params expected a map or struct, got nil")
+		}
+		valueOf = valueOf.Elem()
+	}
+	if valueOf.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("named params expected a map or struct, got %s", valueOf.Kind())
+	}
+
+	params := make(map[string]any)
+	structParams(valueOf, params)
+	return params, nil
+}
+
+func structParams(valueOf reflect.Value, params map[string]any) {
+	typeOf := valueOf.Type()
+	for i := 0; i < valueOf.NumField(); i++ {
+		field := typeOf.Field(i)
+		value := valueOf.Field(i)
+
+		// 嵌入的结构体展开
+		if field.Anonymous && value.Kind() == reflect.Struct && field.Tag.Get("db") == "" {
+			structParams(value, params)
+			continue
+		}
+		if !value.CanInterface() {
+			continue
+		}
+
+		name := strings.Trim(field.Tag.Get("db"), "`")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		params[name] = value.Interface()
+	}
+}
+
+func isNameStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c rune) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
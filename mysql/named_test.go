@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindNamedMap(t *testing.T) {
+	q, args, err := bindNamed(
+		"SELECT * FROM t WHERE user_id = :user_id AND created_at >= :start AND id IN (:ids)",
+		map[string]any{
+			"user_id": 7,
+			"start":   "2024-01-01",
+			"ids":     []int64{1, 2, 3},
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE user_id = ? AND created_at >= ? AND id IN (?,?,?)", q)
+	assert.Equal(t, []any{7, "2024-01-01", int64(1), int64(2), int64(3)}, args)
+}
+
+func TestBindNamedStruct(t *testing.T) {
+	type base struct {
+		Name string `db:"name"`
+	}
+	type params struct {
+		base
+		Val   int `db:"val"`
+		Data  []byte
+		skip  int
+		Other string `db:"-"`
+	}
+
+	q, args, err := bindNamed(
+		"UPDATE t SET data = :Data WHERE name = :name AND val = :val",
+		&params{base: base{Name: "n1"}, Val: 9, Data: []byte("x"), skip: 1},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE t SET data = ? WHERE name = ? AND val = ?", q)
+	assert.Equal(t, []any{[]byte("x"), "n1", 9}, args)
+}
+
+func TestBindNamedQuoteAndEscape(t *testing.T) {
+	q, args, err := bindNamed(
+		"SELECT ':skip', \"a:b\", `c:d`, 'it\\'s :x' FROM t WHERE a = :a AND b::c",
+		map[string]any{"a": 1},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT ':skip', \"a:b\", `c:d`, 'it\\'s :x' FROM t WHERE a = ? AND b:c", q)
+	assert.Equal(t, []any{1}, args)
+}
+
+func TestBindNamedError(t *testing.T) {
+	_, _, err := bindNamed("SELECT * FROM t WHERE a = :a", map[string]any{})
+	assert.NotNil(t, err)
+
+	_, _, err = bindNamed("SELECT * FROM t WHERE id IN (:ids)", map[string]any{"ids": []int{}})
+	assert.NotNil(t, err)
+
+	_, _, err = bindNamed("SELECT 1", 1)
+	assert.NotNil(t, err)
+}
@@ -1,49 +1,135 @@
 package object_cache
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/dawnco/cool/funcs"
+	"github.com/zeromicro/go-zero/core/logx"
 )
 
-// CacheManager 是基于 Cache 封装的带“读写互斥 + 创建工厂”的缓存管理器
+// CacheManager 是基于 Cache 封装的带“按 key 合并创建 + 创建工厂”的缓存管理器
+// 不同 key 并发创建, 同一个 key 并发调用时 factory 只执行一次, 共享结果
+// Close 会关闭所有缓存的对象, 调用方不能在 Close 之后继续使用拿到的对象
 type CacheManager[V any] struct {
 	cache *Cache[V]
-	mu    sync.Mutex
+	mu    sync.Mutex // 保护 calls
+	calls map[string]*createCall[V]
+}
+
+// createCall 正在执行的 factory
+type createCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
 }
 
 func NewCacheManager[V any](liveSeconds, cleanIntervalSeconds int) *CacheManager[V] {
 	return &CacheManager[V]{
 		cache: NewCache[V](liveSeconds, cleanIntervalSeconds),
+		calls: make(map[string]*createCall[V]),
+	}
+}
+
+// NewCacheManagerWithOptions 使用 Options 创建内部缓存
+func NewCacheManagerWithOptions[V any](opts Options[V]) *CacheManager[V] {
+	return &CacheManager[V]{
+		cache: NewCacheWithOptions(opts),
+		calls: make(map[string]*createCall[V]),
 	}
 }
 
 // GetOrCreate 获取指定 key 的缓存对象，若不存在则调用 factory 创建并存入缓存
 func (m *CacheManager[V]) GetOrCreate(key string, factory func() (V, error)) (V, error) {
+	return m.GetOrCreateCtx(context.Background(), key, factory)
+}
+
+// GetOrCreateCtx 同 GetOrCreate, ctx 取消时不再等待返回 ctx.Err()
+// factory 继续执行, 成功后结果仍然写入缓存
+func (m *CacheManager[V]) GetOrCreateCtx(ctx context.Context, key string, factory func() (V, error)) (V, error) {
+	return m.getOrCreate(ctx, key, func() (V, time.Duration, error) {
+		val, err := factory()
+		return val, 0, err
+	})
+}
+
+// GetOrCreateTTL 同 GetOrCreate, factory 同时返回过期时间, 比如 token 使用服务端返回的有效期
+// ttl 为 0 使用缓存的 live
+func (m *CacheManager[V]) GetOrCreateTTL(key string, factory func() (V, time.Duration, error)) (V, error) {
+	return m.getOrCreate(context.Background(), key, factory)
+}
+
+func (m *CacheManager[V]) getOrCreate(ctx context.Context, key string, factory func() (V, time.Duration, error)) (V, error) {
+	if m.cache.closed.Load() {
+		var zero V
+		return zero, ErrCacheClosed
+	}
 	// 先快速查找
 	if val, ok := m.cache.Get(key); ok {
 		return val, nil
 	}
 
-	// 加锁保证同一时刻只有一个 goroutine 执行 factory
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	// 同一个 key 正在创建, 等待结果
+	if call, ok := m.calls[key]; ok {
+		m.mu.Unlock()
+		return m.wait(ctx, call)
+	}
 	// 再次检查缓存，避免重复创建
 	if val, ok := m.cache.Get(key); ok {
+		m.mu.Unlock()
 		return val, nil
 	}
+	call := &createCall[V]{done: make(chan struct{})}
+	m.calls[key] = call
+	m.mu.Unlock()
+
+	// 在单独的 goroutine 执行, 发起的调用也可以因为 ctx 取消不再等待
+	go m.create(key, call, factory)
+	return m.wait(ctx, call)
+}
 
-	// 创建新对象
-	val, err := factory()
+func (m *CacheManager[V]) create(key string, call *createCall[V], factory func() (V, time.Duration, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("cache factory panic: %v", r)
+			logx.Errorf("创建缓存对象 %s panic %v\n%s", key, r, funcs.StackTrace())
+		}
+		// 先写缓存再删除 call, 之后的调用能从缓存读到
+		m.mu.Lock()
+		delete(m.calls, key)
+		m.mu.Unlock()
+		close(call.done)
+	}()
+
+	val, ttl, err := factory()
 	if err != nil {
-		var zero V
-		return zero, err
+		call.err = err
+		return
+	}
+	m.cache.SetWithTTL(key, val, ttl)
+	// 创建期间 Close 了, 写入的对象已经被移除并 Close, 不能再返回
+	if m.cache.closed.Load() {
+		call.err = ErrCacheClosed
+		return
 	}
+	call.val = val
+}
 
-	m.cache.Set(key, val)
-	return val, nil
+func (m *CacheManager[V]) wait(ctx context.Context, call *createCall[V]) (V, error) {
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
 }
 
-// Close 停止内部缓存的清理 goroutine
+// Close 停止内部缓存的清理 goroutine, 移除所有缓存对象, 实现了 io.Closer 的对象会被 Close
+// Close 之后 GetOrCreate 返回 ErrCacheClosed
 func (m *CacheManager[V]) Close() {
 	m.cache.Close()
 }
@@ -1,6 +1,7 @@
 package object_cache
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"sync/atomic"
@@ -163,3 +164,146 @@ func TestCacheManager_GetOrCreate_ConcurrentAccess(t *testing.T) {
 		t.Errorf("expected factory to be called once, but was called %d times", n)
 	}
 }
+
+func TestCacheManager_GetOrCreateTTL(t *testing.T) {
+	mgr := NewCacheManagerWithOptions(Options[string]{
+		LiveSeconds:          60,
+		CleanIntervalSeconds: 60,
+		ExpireMode:           ExpireAbsolute,
+	})
+	defer mgr.Close()
+
+	calls := 0
+	factory := func() (string, time.Duration, error) {
+		calls++
+		return "token", 200 * time.Millisecond, nil
+	}
+
+	mgr.GetOrCreateTTL("t", factory)
+	mgr.GetOrCreateTTL("t", factory)
+	if calls != 1 {
+		t.Fatalf("factory called %d times, expected 1", calls)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	mgr.GetOrCreateTTL("t", factory)
+	if calls != 2 {
+		t.Fatalf("expected factory to be called again after ttl, got %d", calls)
+	}
+}
+
+func TestCacheManager_GetOrCreate_DifferentKeys(t *testing.T) {
+	mgr := NewCacheManager[int](5, 1)
+	defer mgr.Close()
+
+	// key a 的 factory 很慢, 不应该阻塞 key b
+	release := make(chan struct{})
+	go mgr.GetOrCreate("a", func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	defer close(release)
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		mgr.GetOrCreate("b", func() (int, error) {
+			return 2, nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("key b blocked by slow factory of key a")
+	}
+}
+
+func TestCacheManager_GetOrCreateCtx(t *testing.T) {
+	mgr := NewCacheManager[int](5, 1)
+	defer mgr.Close()
+
+	var calls int32
+	factory := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(200 * time.Millisecond)
+		return 7, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := mgr.GetOrCreateCtx(ctx, "slow", factory)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	// factory 继续执行, 后面的调用共享结果
+	val, err := mgr.GetOrCreateCtx(context.Background(), "slow", factory)
+	if err != nil || val != 7 {
+		t.Fatalf("expected 7, got %v %v", val, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected factory to be called once, got %d", n)
+	}
+}
+
+func TestCacheManager_GetOrCreate_Panic(t *testing.T) {
+	mgr := NewCacheManager[int](5, 1)
+	defer mgr.Close()
+
+	_, err := mgr.GetOrCreate("p", func() (int, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("expected error from panic factory")
+	}
+
+	val, err := mgr.GetOrCreate("p", func() (int, error) {
+		return 1, nil
+	})
+	if err != nil || val != 1 {
+		t.Fatalf("expected 1, got %v %v", val, err)
+	}
+}
+
+func TestCacheManager_GetOrCreate_AfterClose(t *testing.T) {
+	mgr := NewCacheManager[*mockResource](60, 60)
+	mgr.Close()
+
+	_, err := mgr.GetOrCreate("a", func() (*mockResource, error) {
+		t.Fatal("factory should not be called after Close")
+		return nil, nil
+	})
+	if !errors.Is(err, ErrCacheClosed) {
+		t.Fatalf("expected ErrCacheClosed, got %v", err)
+	}
+}
+
+func TestCacheManager_GetOrCreate_CloseDuringCreate(t *testing.T) {
+	mgr := NewCacheManager[*mockResource](60, 60)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	res := &mockResource{}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := mgr.GetOrCreate("a", func() (*mockResource, error) {
+			close(started)
+			<-release
+			return res, nil
+		})
+		errCh <- err
+	}()
+
+	<-started
+	mgr.Close()
+	close(release)
+
+	if err := <-errCh; !errors.Is(err, ErrCacheClosed) {
+		t.Fatalf("expected ErrCacheClosed, got %v", err)
+	}
+	if !res.IsClosed() {
+		t.Fatal("expected value created during Close to be closed")
+	}
+}
@@ -1,8 +1,11 @@
 package object_cache
 
 import (
+	"errors"
 	"io"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
@@ -10,19 +13,63 @@ import (
 
 const minLiveBuffer = 100 * time.Millisecond
 
+// ErrCacheClosed 缓存已经 Close
+var ErrCacheClosed = errors.New("object_cache: cache closed")
+
+// Options 缓存选项
+type Options[V any] struct {
+	LiveSeconds          int        // 最大未访问时间（秒）, SetWithTTL 的 ttl 为 0 时也使用这个
+	CleanIntervalSeconds int        // 清理周期（秒）
+	ExpireMode           ExpireMode // 默认的过期方式, 默认 ExpireSliding
+
+	MaxEntries int                             // 最多缓存多少项, 0 不限制
+	MaxCost    int64                           // 所有项的 Weigher 总和上限, 0 不限制, 单项超过上限时只保留这一项
+	Weigher    func(key string, value V) int64 // 每项的大小, 不设置时每项为 1
+	Policy     Policy                          // 超出容量时的淘汰策略, 默认 LRU
+
+	// OnEvict 缓存项移除时调用, 在 io.Closer.Close 之前, 可以用来释放关联的资源或者记录指标
+	OnEvict func(key string, value V, reason EvictReason)
+}
+
 type Cache[V any] struct {
 	data          sync.Map // map[string]*cacheItem[V]
 	liveDuration  time.Duration
 	cleanInterval time.Duration
+	expireMode    ExpireMode
+	onEvict       func(key string, value V, reason EvictReason)
 	stopChan      chan struct{}
+	closed        atomic.Bool
+
+	// 容量限制, 都为 0 时不维护淘汰顺序
+	maxEntries int
+	maxCost    int64
+	weigher    func(key string, value V) int64
+	evictMu    sync.Mutex
+	evictList  *evictHeap[V]
+	cost       int64
+	seq        uint64
 }
 
 // NewCache 创建一个泛型缓存，live 是最大未访问时间（秒），cleanInterval 是清理周期（秒）
 func NewCache[V any](liveSeconds, cleanIntervalSeconds int) *Cache[V] {
+	return NewCacheWithOptions(Options[V]{
+		LiveSeconds:          liveSeconds,
+		CleanIntervalSeconds: cleanIntervalSeconds,
+	})
+}
+
+// NewCacheWithOptions 创建一个泛型缓存, 可以限制容量
+func NewCacheWithOptions[V any](opts Options[V]) *Cache[V] {
 	cache := &Cache[V]{
-		liveDuration:  time.Duration(liveSeconds) * time.Second,
-		cleanInterval: time.Duration(cleanIntervalSeconds) * time.Second,
+		liveDuration:  time.Duration(opts.LiveSeconds) * time.Second,
+		cleanInterval: time.Duration(opts.CleanIntervalSeconds) * time.Second,
+		expireMode:    opts.ExpireMode,
+		onEvict:       opts.OnEvict,
 		stopChan:      make(chan struct{}),
+		maxEntries:    opts.MaxEntries,
+		maxCost:       opts.MaxCost,
+		weigher:       opts.Weigher,
+		evictList:     &evictHeap[V]{lfu: opts.Policy == PolicyLFU},
 	}
 	go cache.startCleaner()
 	return cache
@@ -30,14 +77,79 @@ func NewCache[V any](liveSeconds, cleanIntervalSeconds int) *Cache[V] {
 
 // Set 设置键值
 func (c *Cache[V]) Set(key string, value V) {
+	c.SetWithExpire(key, value, 0, c.expireMode)
+}
+
+// SetWithTTL 设置键值并指定过期时间, 过期方式使用缓存的默认值, ttl 为 0 使用缓存的 live
+func (c *Cache[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	c.SetWithExpire(key, value, ttl, c.expireMode)
+}
+
+// SetWithExpire 设置键值并指定过期时间和过期方式, ttl 为 0 使用缓存的 live
+// Close 之后不再保存, value 按 EvictClosed 移除
+func (c *Cache[V]) SetWithExpire(key string, value V, ttl time.Duration, mode ExpireMode) {
+	if c.closed.Load() {
+		c.evict(key, value, EvictClosed)
+		return
+	}
+	// 和 Close 并发时, 写入后 Close 可能已经遍历过, 自己移除
+	defer func() {
+		if c.closed.Load() {
+			if v, ok := c.data.Load(key); ok {
+				c.removeItem(key, v.(*cacheItem[V]), EvictClosed)
+			}
+		}
+	}()
+
+	now := time.Now()
 	item := &cacheItem[V]{
+		key:        key,
 		value:      value,
-		lastAccess: time.Now(),
+		lastAccess: now,
+		createdAt:  now,
+		ttl:        ttl,
+		mode:       mode,
+		index:      -1,
+	}
+	if !c.bounded() {
+		if old, ok := c.data.Swap(key, item); ok {
+			c.replaced(key, old.(*cacheItem[V]), value)
+		}
+		return
+	}
+
+	if c.weigher != nil {
+		item.cost = c.weigher(key, value)
+	} else {
+		item.cost = 1
+	}
+
+	c.evictMu.Lock()
+	if old, ok := c.data.Load(key); ok {
+		c.unlink(old.(*cacheItem[V]))
+	}
+	evicted := c.makeRoom(item.cost)
+	old, replaced := c.data.Swap(key, item)
+	c.link(item)
+	c.evictMu.Unlock()
+
+	for _, e := range evicted {
+		c.evict(e.key, e.value, EvictCapacity)
+	}
+	if replaced {
+		c.replaced(key, old.(*cacheItem[V]), value)
+	}
+}
+
+// replaced Set 覆盖了旧的值, 同一个值重复 Set 时不移除
+func (c *Cache[V]) replaced(key string, old *cacheItem[V], value V) {
+	if sameValue(old.value, value) {
+		return
 	}
-	c.data.Store(key, item)
+	c.evict(key, old.value, EvictReplaced)
 }
 
-// Get 获取键值，刷新 lastAccess
+// Get 获取键值，刷新 lastAccess, 已经过期的删除
 func (c *Cache[V]) Get(key string) (V, bool) {
 	var zero V
 	v, ok := c.data.Load(key)
@@ -45,21 +157,96 @@ func (c *Cache[V]) Get(key string) (V, bool) {
 		return zero, false
 	}
 	item := v.(*cacheItem[V])
+	if item.expired(c.liveDuration) {
+		c.removeItem(key, item, EvictExpired)
+		return zero, false
+	}
 	item.touch()
+	if c.bounded() {
+		c.evictMu.Lock()
+		c.hit(item)
+		c.evictMu.Unlock()
+	}
 	return item.value, true
 }
 
 // Delete 删除缓存项并调用 Close（如果实现了 io.Closer）
 func (c *Cache[V]) Delete(key string) {
-	v, ok := c.data.LoadAndDelete(key)
+	v, ok := c.data.Load(key)
 	if ok {
-		item := v.(*cacheItem[V])
-		if closer, ok := any(item.value).(io.Closer); ok {
-			if err := closer.Close(); err != nil {
-				logx.Errorf("Error closing cache item %v", err)
-			}
+		c.removeItem(key, v.(*cacheItem[V]), EvictDeleted)
+	}
+}
+
+// Len 缓存项数量
+func (c *Cache[V]) Len() int {
+	if c.bounded() {
+		c.evictMu.Lock()
+		defer c.evictMu.Unlock()
+		return c.evictList.Len()
+	}
+	n := 0
+	c.data.Range(func(key, value any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// removeItem 删除 key 对应的 item, 已经被替换时不删除
+func (c *Cache[V]) removeItem(key string, item *cacheItem[V], reason EvictReason) bool {
+	if c.bounded() {
+		c.evictMu.Lock()
+		deleted := c.data.CompareAndDelete(key, item)
+		if deleted {
+			c.unlink(item)
 		}
+		c.evictMu.Unlock()
+		if !deleted {
+			return false
+		}
+	} else if !c.data.CompareAndDelete(key, item) {
+		return false
 	}
+
+	c.evict(key, item.value, reason)
+	return true
+}
+
+func (c *Cache[V]) bounded() bool {
+	return c.maxEntries > 0 || c.maxCost > 0
+}
+
+// evict 调用 OnEvict 和 Close（如果实现了 io.Closer）
+func (c *Cache[V]) evict(key string, value V, reason EvictReason) {
+	if c.onEvict != nil {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logx.Errorf("cache OnEvict %s panic %v", key, r)
+				}
+			}()
+			c.onEvict(key, value, reason)
+		}()
+	}
+	if closer, ok := any(value).(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logx.Errorf("Error closing cache item %v", err)
+		}
+	}
+}
+
+// sameValue 不可比较的值按不同处理
+// 类型可比较时字段里的 interface 仍然可能是不可比较的值, 需要检查实际的值
+func sameValue[V any](a, b V) bool {
+	va, vb := reflect.ValueOf(any(a)), reflect.ValueOf(any(b))
+	if !va.IsValid() || !vb.IsValid() {
+		return !va.IsValid() && !vb.IsValid()
+	}
+	if va.Type() != vb.Type() || !va.Comparable() || !vb.Comparable() {
+		return false
+	}
+	return any(a) == any(b)
 }
 
 // startCleaner 删除过期缓存
@@ -84,18 +271,25 @@ func (c *Cache[V]) loopClear() {
 			if !ok {
 				return true
 			}
-			c.Delete(k)
+			c.removeItem(k, item, EvictExpired)
 		}
 		return true
 	})
 }
 
-// Close 停止后台清理协程
+// Close 停止后台清理协程, 移除所有缓存项
+// 每一项按 EvictClosed 调用 OnEvict, 实现了 io.Closer 的值会被 Close
+// Close 之后 Set 不再保存, Get 返回不存在
 func (c *Cache[V]) Close() {
-	select {
-	case <-c.stopChan:
+	if !c.closed.CompareAndSwap(false, true) {
 		return
-	default:
-		close(c.stopChan)
 	}
+	close(c.stopChan)
+
+	c.data.Range(func(key, value any) bool {
+		if k, ok := key.(string); ok {
+			c.removeItem(k, value.(*cacheItem[V]), EvictClosed)
+		}
+		return true
+	})
 }
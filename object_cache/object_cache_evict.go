@@ -0,0 +1,129 @@
+package object_cache
+
+import (
+	"container/heap"
+)
+
+// Policy 超出容量时的淘汰策略
+type Policy int
+
+const (
+	PolicyLRU Policy = iota // 淘汰最久没有访问的
+	PolicyLFU               // 淘汰访问次数最少的, 次数相同淘汰最久没有访问的
+)
+
+// EvictReason 缓存项被移除的原因
+type EvictReason int
+
+const (
+	EvictExpired  EvictReason = iota + 1 // 过期
+	EvictCapacity                        // 超出容量被淘汰
+	EvictDeleted                         // 调用 Delete
+	EvictReplaced                        // 被 Set 覆盖
+	EvictClosed                          // 缓存 Close
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "capacity"
+	case EvictDeleted:
+		return "deleted"
+	case EvictReplaced:
+		return "replaced"
+	case EvictClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// evictHeap 按淘汰顺序排列的最小堆, 堆顶是下一个淘汰的
+type evictHeap[V any] struct {
+	items []*cacheItem[V]
+	lfu   bool
+}
+
+func (h *evictHeap[V]) Len() int {
+	return len(h.items)
+}
+
+func (h *evictHeap[V]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.lfu && a.hits != b.hits {
+		return a.hits < b.hits
+	}
+	return a.seq < b.seq
+}
+
+func (h *evictHeap[V]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *evictHeap[V]) Push(x any) {
+	item := x.(*cacheItem[V])
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *evictHeap[V]) Pop() any {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	item.index = -1
+	return item
+}
+
+// 以下方法调用方需要持有 evictMu
+
+func (c *Cache[V]) link(item *cacheItem[V]) {
+	c.seq++
+	item.seq = c.seq
+	c.cost += item.cost
+	heap.Push(c.evictList, item)
+}
+
+func (c *Cache[V]) unlink(item *cacheItem[V]) {
+	if item.index < 0 {
+		return
+	}
+	heap.Remove(c.evictList, item.index)
+	c.cost -= item.cost
+}
+
+// hit 访问后调整淘汰顺序
+func (c *Cache[V]) hit(item *cacheItem[V]) {
+	if item.index < 0 {
+		return
+	}
+	c.seq++
+	item.seq = c.seq
+	item.hits++
+	heap.Fix(c.evictList, item.index)
+}
+
+// overflow 再加入一个 cost 大小的项是否超出容量
+func (c *Cache[V]) overflow(cost int64) bool {
+	n := c.evictList.Len()
+	if n == 0 {
+		return false
+	}
+	return (c.maxEntries > 0 && n+1 > c.maxEntries) || (c.maxCost > 0 && c.cost+cost > c.maxCost)
+}
+
+// makeRoom 为新加入的项淘汰旧的项, 返回被淘汰的
+// 新加入的项不参与淘汰, 否则 LFU 下新的 key 永远进不来
+func (c *Cache[V]) makeRoom(cost int64) []*cacheItem[V] {
+	var evicted []*cacheItem[V]
+	for c.overflow(cost) {
+		item := heap.Pop(c.evictList).(*cacheItem[V])
+		c.cost -= item.cost
+		c.data.CompareAndDelete(item.key, item)
+		evicted = append(evicted, item)
+	}
+	return evicted
+}
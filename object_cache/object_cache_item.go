@@ -5,10 +5,28 @@ import (
 	"time"
 )
 
+// ExpireMode 过期方式
+type ExpireMode int
+
+const (
+	ExpireSliding  ExpireMode = iota // 超过 ttl 没有访问过期, 每次 Get 重新计时
+	ExpireAbsolute                   // 写入后超过 ttl 过期, 不管是否访问
+)
+
 type cacheItem[V any] struct {
 	mu         sync.Mutex
+	key        string
 	value      V
 	lastAccess time.Time
+	createdAt  time.Time
+	ttl        time.Duration // 0 使用缓存的 liveDuration
+	mode       ExpireMode
+
+	// 以下字段在限制容量时使用, 由 Cache.evictMu 保护
+	cost  int64
+	hits  uint64
+	seq   uint64 // 最后访问的序号, 越小越久没有访问
+	index int    // 在淘汰堆中的位置, -1 表示不在堆中
 }
 
 func (i *cacheItem[V]) touch() {
@@ -20,5 +38,11 @@ func (i *cacheItem[V]) touch() {
 func (i *cacheItem[V]) expired(live time.Duration) bool {
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	if i.ttl > 0 {
+		live = i.ttl
+	}
+	if i.mode == ExpireAbsolute {
+		return time.Since(i.createdAt) > live
+	}
 	return time.Since(i.lastAccess) > live+minLiveBuffer
 }
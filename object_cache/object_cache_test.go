@@ -1,6 +1,8 @@
 package object_cache
 
 import (
+	"reflect"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -91,3 +93,245 @@ func TestCacheNoPanicOnNonStringKey(t *testing.T) {
 		t.Fatal("expected key 123 to still be present")
 	}
 }
+
+func TestCacheMaxEntriesLRU(t *testing.T) {
+	cache := NewCacheWithOptions(Options[*mockResource]{
+		LiveSeconds:          60,
+		CleanIntervalSeconds: 60,
+		MaxEntries:           2,
+	})
+	defer cache.Close()
+
+	a, b, c := &mockResource{}, &mockResource{}, &mockResource{}
+	cache.Set("a", a)
+	cache.Set("b", b)
+	cache.Get("a") // a 最近访问过, 淘汰 b
+	cache.Set("c", c)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if !b.IsClosed() {
+		t.Fatal("expected b to be closed after eviction")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a to be present")
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+
+	// 覆盖不增加数量
+	cache.Set("c", &mockResource{})
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries after replace, got %d", cache.Len())
+	}
+}
+
+func TestCacheMaxEntriesLFU(t *testing.T) {
+	cache := NewCacheWithOptions(Options[int]{
+		LiveSeconds:          60,
+		CleanIntervalSeconds: 60,
+		MaxEntries:           2,
+		Policy:               PolicyLFU,
+	})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("b")
+	cache.Set("c", 3) // 新加入的 c 不参与淘汰, 淘汰访问次数最少的 b
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a to be present")
+	}
+}
+
+func TestCacheMaxCost(t *testing.T) {
+	cache := NewCacheWithOptions(Options[string]{
+		LiveSeconds:          60,
+		CleanIntervalSeconds: 60,
+		MaxCost:              10,
+		Weigher: func(key string, value string) int64 {
+			return int64(len(value))
+		},
+	})
+	defer cache.Close()
+
+	cache.Set("a", "12345")
+	cache.Set("b", "1234")
+	cache.Set("c", "123") // 总和 12 > 10, 淘汰 a
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("expected a to be evicted")
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+
+	cache.Delete("b")
+	cache.Set("d", "1234567") // 3 + 7 = 10 不淘汰
+	if _, ok := cache.Get("c"); !ok {
+		t.Fatal("expected c to be present")
+	}
+}
+
+func TestCacheSetWithTTL(t *testing.T) {
+	cache := NewCache[*mockResource](60, 60)
+	defer cache.Close()
+
+	res := &mockResource{}
+	cache.SetWithTTL("short", res, 200*time.Millisecond)
+	cache.Set("long", &mockResource{})
+
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := cache.Get("short"); !ok {
+		t.Fatal("expected short to be present")
+	}
+	// 滑动过期 访问后重新计时
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := cache.Get("short"); !ok {
+		t.Fatal("expected short to be present after touch")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if _, ok := cache.Get("short"); ok {
+		t.Fatal("expected short to be expired")
+	}
+	if !res.IsClosed() {
+		t.Fatal("expected short to be closed after expired")
+	}
+	if _, ok := cache.Get("long"); !ok {
+		t.Fatal("expected long to use cache live")
+	}
+}
+
+func TestCacheAbsoluteExpire(t *testing.T) {
+	cache := NewCacheWithOptions(Options[int]{
+		LiveSeconds:          60,
+		CleanIntervalSeconds: 60,
+		ExpireMode:           ExpireAbsolute,
+	})
+	defer cache.Close()
+
+	cache.SetWithTTL("token", 1, 200*time.Millisecond)
+	cache.SetWithExpire("sliding", 2, 200*time.Millisecond, ExpireSliding)
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+		cache.Get("token")
+		cache.Get("sliding")
+	}
+
+	// 绝对过期 访问不延长
+	if _, ok := cache.Get("token"); ok {
+		t.Fatal("expected token to be expired")
+	}
+	if _, ok := cache.Get("sliding"); !ok {
+		t.Fatal("expected sliding to be present")
+	}
+}
+
+type evictRecord struct {
+	key    string
+	reason EvictReason
+}
+
+func TestCacheOnEvict(t *testing.T) {
+	var mu sync.Mutex
+	var records []evictRecord
+	cache := NewCacheWithOptions(Options[*mockResource]{
+		LiveSeconds:          60,
+		CleanIntervalSeconds: 60,
+		MaxEntries:           2,
+		OnEvict: func(key string, value *mockResource, reason EvictReason) {
+			if value.IsClosed() {
+				t.Errorf("OnEvict %s called after Close", key)
+			}
+			mu.Lock()
+			records = append(records, evictRecord{key, reason})
+			mu.Unlock()
+		},
+	})
+
+	a1, a2 := &mockResource{}, &mockResource{}
+	cache.Set("a", a1)
+	cache.Set("a", a1) // 同一个值不移除
+	cache.Set("a", a2)
+	if !a1.IsClosed() || a2.IsClosed() {
+		t.Fatal("expected replaced value to be closed")
+	}
+
+	cache.Set("b", &mockResource{})
+	cache.Set("c", &mockResource{}) // 淘汰 a
+	cache.Delete("b")
+	cache.SetWithExpire("d", &mockResource{}, time.Millisecond, ExpireAbsolute)
+	time.Sleep(5 * time.Millisecond)
+	cache.Get("d")
+	cache.Close()
+	cache.Close()
+
+	want := []evictRecord{
+		{"a", EvictReplaced},
+		{"a", EvictCapacity},
+		{"b", EvictDeleted},
+		{"d", EvictExpired},
+		{"c", EvictClosed},
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("unexpected evict records %v", records)
+	}
+	if EvictCapacity.String() != "capacity" {
+		t.Fatalf("unexpected reason string %s", EvictCapacity)
+	}
+}
+
+func TestCacheSetReplaceNonComparable(t *testing.T) {
+	cache := NewCache[[]int](60, 60)
+	defer cache.Close()
+
+	cache.Set("a", []int{1})
+	cache.Set("a", []int{2})
+	if v, _ := cache.Get("a"); v[0] != 2 {
+		t.Fatalf("expected replaced value, got %v", v)
+	}
+}
+
+func TestCacheSetReplaceUncomparableValue(t *testing.T) {
+	type box struct{ X any }
+	cache := NewCache[box](60, 60)
+	defer cache.Close()
+
+	// box 类型可比较, 但是 X 是 []int 时 == 会 panic
+	cache.Set("a", box{X: []int{1}})
+	cache.Set("a", box{X: []int{2}})
+	if v, _ := cache.Get("a"); v.X.([]int)[0] != 2 {
+		t.Fatalf("expected replaced value, got %v", v)
+	}
+	if !sameValue(box{X: 1}, box{X: 1}) || sameValue[any](nil, 1) || !sameValue[any](nil, nil) {
+		t.Fatal("unexpected sameValue result")
+	}
+}
+
+func TestCacheSetAfterClose(t *testing.T) {
+	cache := NewCache[*mockResource](60, 60)
+	cache.Set("a", &mockResource{})
+	cache.Close()
+
+	r := &mockResource{}
+	cache.Set("b", r)
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("expected Set after Close not to store")
+	}
+	if !r.IsClosed() {
+		t.Fatal("expected value set after Close to be closed")
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("expected empty cache, got %d", cache.Len())
+	}
+}
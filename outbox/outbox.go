@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dawnco/cool/mysql"
+	"github.com/dawnco/cool/utils"
+)
+
+/*
+
+事务发件箱, 事件和业务数据在同一个事务里写入, 由 Relay 投递到事件中心
+
+CREATE TABLE `event_outbox` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `request_id` varchar(64) NOT NULL DEFAULT '',
+  `name` varchar(128) NOT NULL,
+  `topic` varchar(128) NOT NULL DEFAULT '',
+  `from_name` varchar(128) NOT NULL DEFAULT '',
+  `time_ms` bigint NOT NULL,
+  `params` mediumtext NOT NULL,
+  `status` tinyint NOT NULL DEFAULT 0,
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_at` bigint NOT NULL DEFAULT 0,
+  `sent_at` bigint NOT NULL DEFAULT 0,
+  `last_error` varchar(512) NOT NULL DEFAULT '',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_status_next_at` (`status`,`next_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+*/
+
+const (
+	StatusPending = 0 // 待发送
+	StatusSent    = 1 // 已发送
+	StatusDead    = 2 // 超过重试次数 放弃
+)
+
+// Event 事件, 字段和 utils.ApiEventCenter 参数一致
+type Event struct {
+	RequestId string
+	Name      string
+	Topic     string
+	From      string
+	TimeMs    int64 // 毫秒时间戳 为 0 时使用当前时间
+	Params    any
+}
+
+// Sender 投递事件, 返回错误会按退避时间重试
+type Sender func(ev Event) error
+
+type Outbox struct {
+	db          *mysql.Db
+	table       string
+	sender      Sender
+	batchSize   int
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	lease       time.Duration
+	retention   time.Duration
+}
+
+// New 创建发件箱 db 是发件箱表所在的数据库
+func New(db *mysql.Db) *Outbox {
+	return &Outbox{
+		db:          db,
+		table:       "event_outbox",
+		sender:      eventCenterSender,
+		batchSize:   100,
+		maxAttempts: 10,
+		baseDelay:   time.Second,
+		maxDelay:    10 * time.Minute,
+		lease:       time.Minute,
+		retention:   7 * 24 * time.Hour,
+	}
+}
+
+func (s *Outbox) SetTable(table string) *Outbox {
+	s.table = table
+	return s
+}
+
+func (s *Outbox) SetSender(sender Sender) *Outbox {
+	s.sender = sender
+	return s
+}
+
+// SetBatchSize 每次投递的最大条数
+func (s *Outbox) SetBatchSize(n int) *Outbox {
+	s.batchSize = n
+	return s
+}
+
+// SetMaxAttempts 最大投递次数, 超过后状态改为 StatusDead
+func (s *Outbox) SetMaxAttempts(n int) *Outbox {
+	s.maxAttempts = n
+	return s
+}
+
+// SetBackoff 重试间隔 base 每次翻倍 最大 max
+func (s *Outbox) SetBackoff(base, max time.Duration) *Outbox {
+	s.baseDelay = base
+	s.maxDelay = max
+	return s
+}
+
+// SetLease 认领后多久没有投递结果会被重新认领, 应大于一批事件的投递时间
+func (s *Outbox) SetLease(d time.Duration) *Outbox {
+	s.lease = d
+	return s
+}
+
+// SetRetention 已发送和已放弃事件的保留时间, 超过后 Cleanup 删除
+func (s *Outbox) SetRetention(d time.Duration) *Outbox {
+	s.retention = d
+	return s
+}
+
+// Add 在事务中写入事件, tx 必须是 mysql.Db.Transact 回调里的 tx
+func (s *Outbox) Add(tx *mysql.Db, ev Event) error {
+	if ev.TimeMs == 0 {
+		ev.TimeMs = time.Now().UnixMilli()
+	}
+	params, err := json.Marshal(ev.Params)
+	if err != nil {
+		return fmt.Errorf("outbox 事件参数错误 %w", err)
+	}
+	_, err = tx.Insert(s.table, map[string]any{
+		"request_id": ev.RequestId,
+		"name":       ev.Name,
+		"topic":      ev.Topic,
+		"from_name":  ev.From,
+		"time_ms":    ev.TimeMs,
+		"params":     string(params),
+		"status":     StatusPending,
+		"next_at":    0,
+	})
+	return err
+}
+
+// Scheduler 定时任务, 例如 task.MustScheduler 返回的 *task.Server
+type Scheduler interface {
+	AddIntervalJob(interval time.Duration, task func())
+}
+
+// Register 注册到定时任务 每隔 interval 投递一次并清理过期数据
+func (s *Outbox) Register(server Scheduler, interval time.Duration) {
+	server.AddIntervalJob(interval, s.Relay)
+}
+
+func eventCenterSender(ev Event) error {
+	n := utils.ApiEventCenter(ev.RequestId, ev.Name, ev.Topic, ev.From, ev.TimeMs, ev.Params)
+	if n == 0 {
+		return fmt.Errorf("发送事件 %s 到事件中心失败", ev.Name)
+	}
+	return nil
+}
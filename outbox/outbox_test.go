@@ -0,0 +1,173 @@
+package outbox
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dawnco/cool/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+func newMock(t *testing.T) (*Outbox, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return New(mysql.NewDb(sqlx.NewSqlConnFromDB(db))), mock
+}
+
+// leaseArg 记录认领时的租约, 之后的更新必须带上同一个值
+type leaseArg struct {
+	value *int64
+}
+
+func (a leaseArg) Match(v driver.Value) bool {
+	n, ok := v.(int64)
+	if !ok {
+		return false
+	}
+	if *a.value == 0 {
+		*a.value = n
+	}
+	return n == *a.value
+}
+
+func TestAdd(t *testing.T) {
+	o, mock := newMock(t)
+
+	mock.ExpectExec("INSERT INTO event_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	err := o.Add(o.db, Event{Name: "order.paid", Params: map[string]any{"id": 1}})
+	assert.Nil(t, err)
+
+	err = o.Add(o.db, Event{Name: "bad", Params: func() {}})
+	assert.NotNil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayOnce(t *testing.T) {
+	o, mock := newMock(t)
+
+	var sent []string
+	o.SetSender(func(ev Event) error {
+		sent = append(sent, ev.Name)
+		if ev.Name == "fail" {
+			return errors.New("event center down")
+		}
+		return nil
+	})
+
+	var lease int64
+	columns := []string{"id", "request_id", "name", "topic", "from_name", "time_ms", "params", "attempts"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).
+		WithArgs(StatusPending, sqlmock.AnyArg(), 100).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "r1", "ok", "", "", 1, `{"a":1}`, 0).
+			AddRow(2, "r2", "fail", "", "", 1, `{}`, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE event_outbox SET next_at = ? WHERE id IN (?,?)")).
+		WithArgs(leaseArg{&lease}, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// 事务提交之后才投递, 更新时带上租约
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE event_outbox SET status = ?, attempts = ?, sent_at = ? WHERE id = ? AND status = ? AND next_at = ?")).
+		WithArgs(StatusSent, 1, sqlmock.AnyArg(), 1, StatusPending, leaseArg{&lease}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE event_outbox SET status = ?, attempts = ?, next_at = ?, last_error = ? WHERE id = ? AND status = ? AND next_at = ?")).
+		WithArgs(StatusPending, 1, sqlmock.AnyArg(), "event center down", 2, StatusPending, leaseArg{&lease}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := o.RelayOnce()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"ok", "fail"}, sent)
+	assert.Greater(t, lease, time.Now().UnixMilli())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayOnceLongError(t *testing.T) {
+	o, mock := newMock(t)
+
+	// 中文错误信息按字符截断
+	msg := strings.Repeat("投递失败", 200)
+	o.SetSender(func(ev Event) error {
+		return errors.New(msg)
+	})
+
+	var lease int64
+	columns := []string{"id", "request_id", "name", "topic", "from_name", "time_ms", "params", "attempts"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).
+		WithArgs(StatusPending, sqlmock.AnyArg(), 100).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "r3", "fail", "", "", 1, `{}`, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE event_outbox SET next_at = ? WHERE id IN (?)")).
+		WithArgs(leaseArg{&lease}, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE event_outbox SET status = ?, attempts = ?, next_at = ?, last_error = ? WHERE id = ? AND status = ? AND next_at = ?")).
+		WithArgs(StatusPending, 1, sqlmock.AnyArg(), string([]rune(msg)[:512]), 3, StatusPending, leaseArg{&lease}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := o.RelayOnce()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayOnceEmpty(t *testing.T) {
+	o, mock := newMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	n, err := o.RelayOnce()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkFailed(t *testing.T) {
+	o, mock := newMock(t)
+	o.SetMaxAttempts(3)
+
+	query := regexp.QuoteMeta("UPDATE event_outbox SET status = ?, attempts = ?, next_at = ?, last_error = ? WHERE id = ? AND status = ? AND next_at = ?")
+	mock.ExpectExec(query).
+		WithArgs(StatusPending, 2, sqlmock.AnyArg(), "timeout", 7, StatusPending, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, o.markFailed(outboxRow{Id: 7, Attempts: 1}, 100, errors.New("timeout")))
+
+	// 超过最大次数放弃, 错误信息截断
+	long := make([]byte, 600)
+	for i := range long {
+		long[i] = 'x'
+	}
+	mock.ExpectExec(query).
+		WithArgs(StatusDead, 3, sqlmock.AnyArg(), string(long[:512]), 7, StatusPending, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, o.markFailed(outboxRow{Id: 7, Attempts: 2}, 100, errors.New(string(long))))
+
+	mock.ExpectExec(query).WillReturnError(sql.ErrConnDone)
+	assert.NotNil(t, o.markFailed(outboxRow{Id: 7}, 100, errors.New("timeout")))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCleanup(t *testing.T) {
+	o, mock := newMock(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM event_outbox WHERE (status = ? AND sent_at < ?) OR (status = ? AND next_at < ?)")).
+		WithArgs(StatusSent, sqlmock.AnyArg(), StatusDead, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := o.Cleanup()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dawnco/cool/mysql"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type outboxRow struct {
+	Id        int64  `db:"id"`
+	RequestId string `db:"request_id"`
+	Name      string `db:"name"`
+	Topic     string `db:"topic"`
+	FromName  string `db:"from_name"`
+	TimeMs    int64  `db:"time_ms"`
+	Params    string `db:"params"`
+	Attempts  int    `db:"attempts"`
+}
+
+// Relay 投递待发送事件并清理过期数据, 可直接作为定时任务
+func (s *Outbox) Relay() {
+	for {
+		n, err := s.RelayOnce()
+		if err != nil {
+			logx.Errorf("outbox 投递事件失败 %s", err.Error())
+			break
+		}
+		// 不满一批说明没有积压了
+		if n < s.batchSize {
+			break
+		}
+	}
+
+	if _, err := s.Cleanup(); err != nil {
+		logx.Errorf("outbox 清理事件失败 %s", err.Error())
+	}
+}
+
+// RelayOnce 投递一批到期的事件, 返回处理的条数
+// 先在短事务里用 FOR UPDATE SKIP LOCKED 认领, 把 next_at 改成租约到期时间, 然后在事务外投递
+// 多个实例同时运行不会重复投递, 投递超过租约时间没有结果的事件会被重新认领
+func (s *Outbox) RelayOnce() (int, error) {
+	rows, lease, err := s.claim()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		ev := Event{
+			RequestId: row.RequestId,
+			Name:      row.Name,
+			Topic:     row.Topic,
+			From:      row.FromName,
+			TimeMs:    row.TimeMs,
+			Params:    json.RawMessage(row.Params),
+		}
+		if err := s.sender(ev); err != nil {
+			if err := s.markFailed(row, lease, err); err != nil {
+				return len(rows), err
+			}
+			continue
+		}
+		if err := s.markSent(row, lease); err != nil {
+			return len(rows), err
+		}
+	}
+	return len(rows), nil
+}
+
+// claim 认领一批到期的事件, 返回事件和租约到期时间
+func (s *Outbox) claim() ([]outboxRow, int64, error) {
+	var rows []outboxRow
+	now := time.Now()
+	lease := now.Add(s.lease).UnixMilli()
+	err := s.db.Transact(func(tx *mysql.Db) error {
+		query := fmt.Sprintf("SELECT id, request_id, name, topic, from_name, time_ms, params, attempts FROM %s"+
+			" WHERE status = ? AND next_at <= ? ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED", s.table)
+		err := tx.GetData(&rows, query, StatusPending, now.UnixMilli(), s.batchSize)
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			ids[i] = row.Id
+		}
+		query = fmt.Sprintf("UPDATE %s SET next_at = :lease WHERE id IN (:ids)", s.table)
+		_, err = tx.ExecNamed(query, map[string]any{"lease": lease, "ids": ids})
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, lease, nil
+}
+
+// markSent 投递成功, 租约已经被其他实例重新认领时不更新
+func (s *Outbox) markSent(row outboxRow, lease int64) error {
+	query := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, sent_at = ? WHERE id = ? AND status = ? AND next_at = ?", s.table)
+	_, err := s.db.Exec(query, StatusSent, row.Attempts+1, time.Now().UnixMilli(), row.Id, StatusPending, lease)
+	return err
+}
+
+func (s *Outbox) markFailed(row outboxRow, lease int64, sendErr error) error {
+	attempts := row.Attempts + 1
+	status := StatusPending
+	if attempts >= s.maxAttempts {
+		status = StatusDead
+		logx.Errorf("outbox 事件 %d %s 投递 %d 次失败 放弃 %s", row.Id, row.Name, attempts, sendErr.Error())
+	}
+
+	// 按字符截断, 按字节截断会切开多字节字符, 严格模式下 MySQL 拒绝写入
+	lastError := sendErr.Error()
+	if runes := []rune(lastError); len(runes) > 512 {
+		lastError = string(runes[:512])
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, next_at = ?, last_error = ? WHERE id = ? AND status = ? AND next_at = ?", s.table)
+	_, err := s.db.Exec(query, status, attempts, time.Now().Add(s.backoff(attempts)).UnixMilli(), lastError,
+		row.Id, StatusPending, lease)
+	return err
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (s *Outbox) backoff(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.maxDelay {
+			return s.maxDelay
+		}
+	}
+	return delay
+}
+
+// Cleanup 删除超过保留时间的已发送和已放弃的事件
+// 已放弃的事件按最后一次失败的时间计算
+func (s *Outbox) Cleanup() (int64, error) {
+	before := time.Now().Add(-s.retention).UnixMilli()
+	query := fmt.Sprintf("DELETE FROM %s WHERE (status = ? AND sent_at < ?) OR (status = ? AND next_at < ?) LIMIT 1000", s.table)
+	result, err := s.db.Exec(query, StatusSent, before, StatusDead, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
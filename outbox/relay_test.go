@@ -0,0 +1,18 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	o := New(nil).SetBackoff(time.Second, 10*time.Second)
+
+	assert.Equal(t, time.Second, o.backoff(1))
+	assert.Equal(t, 2*time.Second, o.backoff(2))
+	assert.Equal(t, 8*time.Second, o.backoff(4))
+	assert.Equal(t, 10*time.Second, o.backoff(5))
+	assert.Equal(t, 10*time.Second, o.backoff(100))
+}
@@ -8,9 +8,17 @@ import (
 	"github.com/zeromicro/go-zero/core/service"
 )
 
+// Leader 选主, 例如 wredis.LeaderElector
+type Leader interface {
+	IsLeader() bool
+}
+
+var _ service.Service = (*Server)(nil)
+
 type Server struct {
 	scheduler gocron.Scheduler
 	stopSig   chan int
+	leader    Leader
 }
 
 func (s *Server) Start() {
@@ -33,12 +41,17 @@ func (s *Server) Stop() {
 	}
 }
 
+// SetLeader 多实例部署时设置, 只有 leader 执行任务, 需要在 Start 之前设置
+func (s *Server) SetLeader(leader Leader) {
+	s.leader = leader
+}
+
 // AddJob rule 格式 "8 8 * * *" task 定时执行的任务
 func (s *Server) AddJob(rule string, task func()) {
 
 	_, err := s.scheduler.NewJob(
 		gocron.CronJob(rule, false),
-		gocron.NewTask(task),
+		gocron.NewTask(s.leaderOnly(task)),
 	)
 
 	if err != nil {
@@ -46,7 +59,31 @@ func (s *Server) AddJob(rule string, task func()) {
 	}
 }
 
-func MustScheduler(loc string) service.Service {
+// AddIntervalJob 每隔 interval 执行一次 task, 上一次没执行完会跳过本次
+func (s *Server) AddIntervalJob(interval time.Duration, task func()) {
+
+	_, err := s.scheduler.NewJob(
+		gocron.DurationJob(interval),
+		gocron.NewTask(s.leaderOnly(task)),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+
+	if err != nil {
+		logx.Errorf("添加定时任务失败 %s", err.Error())
+	}
+}
+
+// leaderOnly 设置了 leader 时 不是 leader 跳过本次执行
+func (s *Server) leaderOnly(task func()) func() {
+	return func() {
+		if s.leader != nil && !s.leader.IsLeader() {
+			return
+		}
+		task()
+	}
+}
+
+func MustScheduler(loc string) *Server {
 
 	location, err := time.LoadLocation(loc)
 	if err != nil {
@@ -0,0 +1,162 @@
+package wredis
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redis bitmap 最大 2^32 位
+const bloomMaxBits = 1 << 32
+
+// ARGV[1] 是每个元素的位数 k, 之后每 k 个是一个元素的位
+// 返回每个元素是否新加入
+var bloomAddScript = redis.NewScript(`
+local k = tonumber(ARGV[1])
+local res = {}
+for i = 0, (#ARGV - 1) / k - 1 do
+	local added = 0
+	for j = 1, k do
+		if redis.call('setbit', KEYS[1], ARGV[1 + i * k + j], 1) == 0 then
+			added = 1
+		end
+	end
+	res[i + 1] = added
+end
+return res
+`)
+
+var bloomExistsScript = redis.NewScript(`
+local k = tonumber(ARGV[1])
+local res = {}
+for i = 0, (#ARGV - 1) / k - 1 do
+	local exists = 1
+	for j = 1, k do
+		if redis.call('getbit', KEYS[1], ARGV[1 + i * k + j]) == 0 then
+			exists = 0
+			break
+		end
+	end
+	res[i + 1] = exists
+end
+return res
+`)
+
+// BloomFilter 基于 redis bitmap 的布隆过滤器
+// Exists 返回 false 时一定不存在, 返回 true 时可能存在
+type BloomFilter struct {
+	client redis.UniversalClient
+	key    string
+	m      uint64
+	k      int
+}
+
+// NewBloomFilter name 是 Init 的配置名称, key 是 bitmap 的 redis key
+// n 是预计元素数量, p 是误判率, 例如 0.01
+func NewBloomFilter(name, key string, n uint64, p float64) *BloomFilter {
+	m, k := bloomSize(n, p)
+	return &BloomFilter{
+		client: Get(name),
+		key:    key,
+		m:      m,
+		k:      k,
+	}
+}
+
+// bloomSize m = -n*ln(p)/ln2^2, k = m/n*ln2
+func bloomSize(n uint64, p float64) (uint64, int) {
+	if n == 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	m = min(m, bloomMaxBits)
+	k := max(int(math.Round(m/float64(n)*math.Ln2)), 1)
+	return uint64(m), k
+}
+
+// Bits bitmap 位数
+func (b *BloomFilter) Bits() uint64 {
+	return b.m
+}
+
+// Hashes 每个元素的位数
+func (b *BloomFilter) Hashes() int {
+	return b.k
+}
+
+// Add 加入元素, 返回是否新加入
+func (b *BloomFilter) Add(ctx context.Context, item string) (bool, error) {
+	res, err := b.MultiAdd(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	return res[0], nil
+}
+
+// MultiAdd 批量加入, 在一次 lua 调用中完成
+func (b *BloomFilter) MultiAdd(ctx context.Context, items ...string) ([]bool, error) {
+	return b.run(ctx, bloomAddScript, items)
+}
+
+// Exists 元素是否可能存在
+func (b *BloomFilter) Exists(ctx context.Context, item string) (bool, error) {
+	res, err := b.MultiExists(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	return res[0], nil
+}
+
+// MultiExists 批量判断, 在一次 lua 调用中完成
+func (b *BloomFilter) MultiExists(ctx context.Context, items ...string) ([]bool, error) {
+	return b.run(ctx, bloomExistsScript, items)
+}
+
+// Clear 删除过滤器
+func (b *BloomFilter) Clear(ctx context.Context) error {
+	return b.client.Del(ctx, b.key).Err()
+}
+
+func (b *BloomFilter) run(ctx context.Context, script *redis.Script, items []string) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, 1+len(items)*b.k)
+	args = append(args, b.k)
+	for _, item := range items {
+		for _, offset := range b.offsets(item) {
+			args = append(args, offset)
+		}
+	}
+
+	res, err := script.Run(ctx, b.client, []string{b.key}, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(res))
+	for i, v := range res {
+		result[i] = v == 1
+	}
+	return result, nil
+}
+
+// offsets 双重哈希 h1 + i*h2, h1 h2 是 fnv128a 的高低 64 位
+func (b *BloomFilter) offsets(item string) []uint64 {
+	h := fnv.New128a()
+	h.Write([]byte(item))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:])
+
+	offsets := make([]uint64, b.k)
+	for i := range offsets {
+		offsets[i] = (h1 + uint64(i)*h2) % b.m
+	}
+	return offsets
+}
@@ -0,0 +1,82 @@
+package wredis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomSize(t *testing.T) {
+	m, k := bloomSize(1000, 0.01)
+	assert.Equal(t, uint64(9586), m)
+	assert.Equal(t, 7, k)
+
+	m, _ = bloomSize(1<<40, 0.0001)
+	assert.Equal(t, uint64(bloomMaxBits), m)
+}
+
+func TestBloomFilter(t *testing.T) {
+	setupRedis(t, "bloom")
+	ctx := context.Background()
+
+	bf := NewBloomFilter("bloom", "bf:user", 1000, 0.01)
+
+	added, err := bf.Add(ctx, "u1")
+	assert.Nil(t, err)
+	assert.True(t, added)
+	added, _ = bf.Add(ctx, "u1")
+	assert.False(t, added)
+
+	items := make([]string, 500)
+	for i := range items {
+		items[i] = "id-" + strconv.Itoa(i)
+	}
+	res, err := bf.MultiAdd(ctx, items...)
+	assert.Nil(t, err)
+	assert.Len(t, res, 500)
+
+	res, err = bf.MultiExists(ctx, items...)
+	assert.Nil(t, err)
+	for _, ok := range res {
+		assert.True(t, ok)
+	}
+
+	falsePositive := 0
+	for i := 0; i < 1000; i++ {
+		if ok, _ := bf.Exists(ctx, "none-"+strconv.Itoa(i)); ok {
+			falsePositive++
+		}
+	}
+	assert.Less(t, falsePositive, 30)
+
+	assert.Nil(t, bf.Clear(ctx))
+	ok, _ := bf.Exists(ctx, "u1")
+	assert.False(t, ok)
+}
+
+func TestTypedCacheBloom(t *testing.T) {
+	setupRedis(t, "bloom")
+	ctx := context.Background()
+
+	bf := NewBloomFilter("bloom", "bf:order", 100, 0.01)
+	c := NewTypedCache[string]("bloom", "order").SetBloom(bf)
+
+	calls := 0
+	loader := func(ctx context.Context) (string, error) {
+		calls++
+		return "order", nil
+	}
+
+	_, err := c.GetOrLoad(ctx, "1", time.Minute, loader)
+	assert.ErrorIs(t, err, ErrNotExist)
+	assert.Equal(t, 0, calls)
+
+	_, _ = bf.Add(ctx, "1")
+	v, err := c.GetOrLoad(ctx, "1", time.Minute, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, "order", v)
+	assert.Equal(t, 1, calls)
+}
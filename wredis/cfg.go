@@ -1,8 +1,40 @@
 package wredis
 
+import "time"
+
+const (
+	ModeSingle   = "single"   // 单节点
+	ModeSentinel = "sentinel" // 哨兵
+	ModeCluster  = "cluster"  // 集群
+)
+
+// Cfg redis 配置
+// 可以用 env.LoadPrefix(&cfg, "REDIS_") 从环境变量读取
 type Cfg struct {
-	Host     string `json:",default=127.0.0.1,optional"`
-	Port     int    `json:",default=6379,optional"`
-	Password string `json:",optional"`
-	Db       int    `json:",default=1,optional"`
+	Mode     string `json:",default=single,options=single|sentinel|cluster" env:"MODE" default:"single"`
+	Host     string `json:",default=127.0.0.1,optional" env:"HOST" default:"127.0.0.1"`
+	Port     int    `json:",default=6379,optional" env:"PORT" default:"6379"`
+	Password string `json:",optional" env:"PASSWORD"`
+	Db       int    `json:",default=1,optional" env:"DB" default:"1"` // cluster 模式不支持选择 db
+
+	Addrs            []string `json:",optional" env:"ADDRS"`             // sentinel 模式是哨兵地址, cluster 模式是节点地址, 格式 host:port
+	MasterName       string   `json:",optional" env:"MASTER_NAME"`       // sentinel 模式的 master 名称
+	SentinelPassword string   `json:",optional" env:"SENTINEL_PASSWORD"` // 哨兵的密码
+	Username         string   `json:",optional" env:"USERNAME"`          // ACL 用户名
+
+	KeyPrefix string `json:",optional" env:"KEY_PREFIX"` // 所有 key 自动加上的前缀 比如 "order:", 多个服务共用一个 db 时避免冲突
+
+	Tls           bool `json:",optional" env:"TLS"`             // 是否使用 TLS 连接
+	TlsSkipVerify bool `json:",optional" env:"TLS_SKIP_VERIFY"` // 不校验服务端证书
+
+	PoolSize     int           `json:",optional" env:"POOL_SIZE"`      // 连接池大小 0 使用默认值 10*CPU数
+	MinIdleConns int           `json:",optional" env:"MIN_IDLE_CONNS"` // 最小空闲连接数
+	DialTimeout  time.Duration `json:",optional" env:"DIAL_TIMEOUT"`   // 0 使用默认值 5s
+	ReadTimeout  time.Duration `json:",optional" env:"READ_TIMEOUT"`   // 0 使用默认值 3s
+	WriteTimeout time.Duration `json:",optional" env:"WRITE_TIMEOUT"`  // 0 使用 ReadTimeout
+	PoolTimeout  time.Duration `json:",optional" env:"POOL_TIMEOUT"`   // 0 使用 ReadTimeout + 1s
+
+	Metrics       bool          `json:",optional" env:"METRICS"`        // 记录每个命令的耗时和错误数
+	SlowThreshold time.Duration `json:",optional" env:"SLOW_THRESHOLD"` // 超过这个时间的命令打慢日志 0 不记录
+	BigValueSize  int           `json:",optional" env:"BIG_VALUE_SIZE"` // SET GET 的值超过多少字节打日志 0 不检查
 }
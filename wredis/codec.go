@@ -0,0 +1,85 @@
+package wredis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dawnco/cool/utils"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec 缓存值的编码方式
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JsonCodec json 编码
+type JsonCodec struct{}
+
+func (JsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec msgpack 编码 比 json 更小更快
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+const (
+	gzipFlagRaw  byte = 0
+	gzipFlagGzip byte = 1
+)
+
+// GzipCodec 在 Codec 的基础上压缩, 编码后超过 MinSize 字节才压缩
+// 数据第一个字节标记是否压缩
+type GzipCodec struct {
+	Codec   Codec
+	MinSize int
+}
+
+func (c GzipCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < c.MinSize {
+		return append([]byte{gzipFlagRaw}, data...), nil
+	}
+
+	gz, err := utils.CompressGz(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{gzipFlagGzip}, gz...), nil
+}
+
+func (c GzipCodec) Unmarshal(data []byte, v any) error {
+	if len(data) == 0 {
+		return fmt.Errorf("gzip codec empty data")
+	}
+
+	switch data[0] {
+	case gzipFlagRaw:
+		return c.Codec.Unmarshal(data[1:], v)
+	case gzipFlagGzip:
+		raw, err := utils.DecompressGz(data[1:])
+		if err != nil {
+			return err
+		}
+		return c.Codec.Unmarshal(raw, v)
+	default:
+		return fmt.Errorf("gzip codec unknown flag %d", data[0])
+	}
+}
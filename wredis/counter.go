@@ -0,0 +1,153 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// WindowCounter 滑动窗口计数, 按 bucket (比如分钟 小时) 分桶计数
+// 每个桶是一个 key, 超过 retention 自动过期
+// 同一个计数对象的桶使用 {key} 作为 hash tag, cluster 模式下可以一次读取
+type WindowCounter struct {
+	client    redis.UniversalClient
+	prefix    string
+	bucket    time.Duration
+	retention time.Duration
+}
+
+// NewWindowCounter name 是 Init 的配置名称, prefix 是 redis key 前缀
+// bucket 是桶的时间粒度, 最小 1ms, retention 是数据保留时间, 也是能统计的最大窗口
+func NewWindowCounter(name, prefix string, bucket, retention time.Duration) *WindowCounter {
+	if bucket < time.Millisecond {
+		panic("wredis: counter bucket must be at least 1ms")
+	}
+	return &WindowCounter{
+		client:    Get(name),
+		prefix:    prefix,
+		bucket:    bucket,
+		retention: retention,
+	}
+}
+
+// Incr 当前桶增加 n
+func (c *WindowCounter) Incr(ctx context.Context, key string, n int64) error {
+	bucketKey := c.bucketKey(key, time.Now())
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.IncrBy(ctx, bucketKey, n)
+		pipe.Expire(ctx, bucketKey, c.retention+c.bucket)
+		return nil
+	})
+	return err
+}
+
+// Count 最近 window 时间内的计数, 包含当前桶
+func (c *WindowCounter) Count(ctx context.Context, key string, window time.Duration) (int64, error) {
+	window = min(window, c.retention)
+	n := int(window / c.bucket)
+	if n < 1 {
+		n = 1
+	}
+
+	now := time.Now()
+	keys := make([]string, n)
+	for i := 0; i < n; i++ {
+		keys[i] = c.bucketKey(key, now.Add(-time.Duration(i)*c.bucket))
+	}
+
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (c *WindowCounter) bucketKey(key string, t time.Time) string {
+	// 按毫秒计算, 整秒的 bucket 和按秒计算的结果相同
+	return fmt.Sprintf("%s:{%s}:%d", c.prefix, key, t.UnixMilli()/c.bucket.Milliseconds())
+}
+
+// UniqueCounter 基于 HyperLogLog 的去重计数, 比如 UV, 误差约 0.81%
+// cluster 模式下 Count 和 Merge 多个 key 时, key 需要使用相同的 hash tag
+type UniqueCounter struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewUniqueCounter name 是 Init 的配置名称, ttl 为 0 不过期
+func NewUniqueCounter(name, prefix string, ttl time.Duration) *UniqueCounter {
+	return &UniqueCounter{
+		client: Get(name),
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Add 添加成员
+func (c *UniqueCounter) Add(ctx context.Context, key string, members ...string) error {
+	args := make([]any, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+
+	k := c.key(key)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.PFAdd(ctx, k, args...)
+		if c.ttl > 0 {
+			pipe.Expire(ctx, k, c.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// Count 去重数量, 多个 key 时返回并集的数量
+func (c *UniqueCounter) Count(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, errors.New("unique counter count requires keys")
+	}
+	return c.client.PFCount(ctx, c.keys(keys)...).Result()
+}
+
+// Merge 把 keys 合并到 dest, 比如把每天的 UV 合并成每周
+func (c *UniqueCounter) Merge(ctx context.Context, dest string, keys ...string) error {
+	k := c.key(dest)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.PFMerge(ctx, k, c.keys(keys)...)
+		if c.ttl > 0 {
+			pipe.Expire(ctx, k, c.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (c *UniqueCounter) key(key string) string {
+	return c.prefix + ":" + key
+}
+
+func (c *UniqueCounter) keys(keys []string) []string {
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = c.key(k)
+	}
+	return result
+}
@@ -0,0 +1,269 @@
+package wredis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dawnco/cool/funcs"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 队列使用三个 key, 名称带 {queue} 保证 cluster 模式下在同一个 slot
+// zset  任务ID => 执行时间毫秒
+// data  任务ID => payload
+// tries 任务ID => 投递次数
+var (
+	delayClaimScript = redis.NewScript(`
+local ids = redis.call('zrangebyscore', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local res = {}
+for _, id in ipairs(ids) do
+	local data = redis.call('hget', KEYS[2], id)
+	if data then
+		redis.call('zadd', KEYS[1], ARGV[2], id)
+		local n = redis.call('hincrby', KEYS[3], id, 1)
+		table.insert(res, id)
+		table.insert(res, data)
+		table.insert(res, n)
+	else
+		redis.call('zrem', KEYS[1], id)
+	end
+end
+return res`)
+
+	// 处理完成后删除, 只有分数和投递次数都和取出时一样才删除
+	// 处理期间重新 Push 或者超时被其他 worker 取走的任务不删除
+	delayAckScript = redis.NewScript(`
+local score = redis.call('zscore', KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+if tonumber(redis.call('hget', KEYS[3], ARGV[1])) ~= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('zrem', KEYS[1], ARGV[1])
+redis.call('hdel', KEYS[2], ARGV[1])
+redis.call('hdel', KEYS[3], ARGV[1])
+return 1`)
+
+	delayRemoveScript = redis.NewScript(`
+local n = redis.call('zrem', KEYS[1], ARGV[1])
+redis.call('hdel', KEYS[2], ARGV[1])
+redis.call('hdel', KEYS[3], ARGV[1])
+return n`)
+)
+
+// DelayJob 延迟任务
+type DelayJob[T any] struct {
+	Id       string
+	Payload  T
+	Attempts int64 // 第几次投递 从 1 开始
+
+	lease int64 // 取出时设置的分数, 完成时用来确认任务没有被重新 Push 或者取走
+}
+
+// DelayHandler 处理任务, 返回错误或者超过 visibility 没处理完会重新投递
+type DelayHandler[T any] func(ctx context.Context, job DelayJob[T]) error
+
+// DelayQueue 延迟队列, 至少投递一次, 实现了 go-zero service.Service
+type DelayQueue[T any] struct {
+	client     redis.UniversalClient
+	zsetKey    string
+	dataKey    string
+	triesKey   string
+	handler    DelayHandler[T]
+	visibility time.Duration
+	interval   time.Duration
+	batch      int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDelayQueue name 是 Init 的配置名称, queue 是队列名称
+func NewDelayQueue[T any](name, queue string) *DelayQueue[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DelayQueue[T]{
+		client:     Get(name),
+		zsetKey:    fmt.Sprintf("delay:{%s}:zset", queue),
+		dataKey:    fmt.Sprintf("delay:{%s}:data", queue),
+		triesKey:   fmt.Sprintf("delay:{%s}:tries", queue),
+		visibility: 30 * time.Second,
+		interval:   time.Second,
+		batch:      20,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Handle 设置任务处理函数
+func (q *DelayQueue[T]) Handle(handler DelayHandler[T]) *DelayQueue[T] {
+	q.handler = handler
+	return q
+}
+
+// SetVisibility 任务被取出后多久没有完成会重新投递
+func (q *DelayQueue[T]) SetVisibility(d time.Duration) *DelayQueue[T] {
+	q.visibility = d
+	return q
+}
+
+// SetPollInterval 轮询间隔
+func (q *DelayQueue[T]) SetPollInterval(d time.Duration) *DelayQueue[T] {
+	q.interval = d
+	return q
+}
+
+// SetBatch 每次最多取出的任务数
+func (q *DelayQueue[T]) SetBatch(n int) *DelayQueue[T] {
+	q.batch = n
+	return q
+}
+
+// Push 添加任务 delay 后执行, id 为空时自动生成, 相同 id 会覆盖之前的任务
+func (q *DelayQueue[T]) Push(ctx context.Context, id string, payload T, delay time.Duration) (string, error) {
+	return q.PushAt(ctx, id, payload, time.Now().Add(delay))
+}
+
+// PushAt 添加任务 在 at 时间执行
+func (q *DelayQueue[T]) PushAt(ctx context.Context, id string, payload T, at time.Time) (string, error) {
+	if id == "" {
+		id = newToken()
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.dataKey, id, data)
+		pipe.HDel(ctx, q.triesKey, id)
+		pipe.ZAdd(ctx, q.zsetKey, redis.Z{Score: float64(at.UnixMilli()), Member: id})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Cancel 取消任务, 任务不存在返回 false
+func (q *DelayQueue[T]) Cancel(ctx context.Context, id string) (bool, error) {
+	n, err := q.remove(ctx, id)
+	return n > 0, err
+}
+
+// Start 开始轮询 阻塞直到 Stop
+func (q *DelayQueue[T]) Start() {
+	if q.handler == nil {
+		logx.Errorf("延迟队列 %s 没有设置处理函数", q.zsetKey)
+		return
+	}
+
+	q.wg.Add(1)
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+			q.poll()
+		}
+	}
+}
+
+// Stop 停止轮询 等待处理中的任务完成
+func (q *DelayQueue[T]) Stop() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *DelayQueue[T]) poll() {
+	for q.ctx.Err() == nil {
+		jobs, err := q.claim(q.ctx)
+		if err != nil {
+			if q.ctx.Err() == nil {
+				logx.Errorf("延迟队列 %s 取任务失败 %s", q.zsetKey, err.Error())
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				q.process(job)
+			}()
+		}
+		wg.Wait()
+
+		if len(jobs) < q.batch {
+			return
+		}
+	}
+}
+
+func (q *DelayQueue[T]) claim(ctx context.Context) ([]DelayJob[T], error) {
+	now := time.Now()
+	lease := now.Add(q.visibility).UnixMilli()
+	res, err := delayClaimScript.Run(ctx, q.client, []string{q.zsetKey, q.dataKey, q.triesKey},
+		now.UnixMilli(), lease, q.batch).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]DelayJob[T], 0, len(res)/3)
+	for i := 0; i+2 < len(res); i += 3 {
+		id, _ := res[i].(string)
+		data, _ := res[i+1].(string)
+		attempts, _ := res[i+2].(int64)
+
+		job := DelayJob[T]{Id: id, Attempts: attempts, lease: lease}
+		if err := json.Unmarshal([]byte(data), &job.Payload); err != nil {
+			logx.Errorf("延迟队列 %s 任务 %s 数据错误 丢弃 %s", q.zsetKey, id, err.Error())
+			_, _ = q.ack(ctx, job)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (q *DelayQueue[T]) process(job DelayJob[T]) {
+	ctx := context.WithoutCancel(q.ctx)
+	if err := q.handle(ctx, job); err != nil {
+		logx.Errorf("延迟队列 %s 任务 %s 第 %d 次处理失败 %s", q.zsetKey, job.Id, job.Attempts, err.Error())
+		return
+	}
+	if _, err := q.ack(ctx, job); err != nil {
+		logx.Errorf("延迟队列 %s 任务 %s 删除失败 %s", q.zsetKey, job.Id, err.Error())
+	}
+}
+
+func (q *DelayQueue[T]) handle(ctx context.Context, job DelayJob[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic %v\n%s", r, funcs.StackTrace())
+		}
+	}()
+	return q.handler(ctx, job)
+}
+
+// ack 删除处理完成的任务, 任务已经被重新 Push 或者取走时返回 false
+func (q *DelayQueue[T]) ack(ctx context.Context, job DelayJob[T]) (bool, error) {
+	n, err := delayAckScript.Run(ctx, q.client, []string{q.zsetKey, q.dataKey, q.triesKey},
+		job.Id, job.lease, job.Attempts).Int64()
+	return n > 0, err
+}
+
+func (q *DelayQueue[T]) remove(ctx context.Context, id string) (int64, error) {
+	return delayRemoveScript.Run(ctx, q.client, []string{q.zsetKey, q.dataKey, q.triesKey}, id).Int64()
+}
@@ -0,0 +1,113 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderTimeout struct {
+	OrderId int64
+}
+
+func TestDelayQueue(t *testing.T) {
+	setupRedis(t, "delay")
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	done := map[int64]int64{}
+
+	q := NewDelayQueue[orderTimeout]("delay", "order").
+		SetPollInterval(20 * time.Millisecond).
+		SetVisibility(50 * time.Millisecond).
+		Handle(func(ctx context.Context, job DelayJob[orderTimeout]) error {
+			// 第一次失败 等 visibility 后重新投递
+			if job.Payload.OrderId == 2 && job.Attempts == 1 {
+				return errors.New("retry later")
+			}
+			mu.Lock()
+			done[job.Payload.OrderId] = job.Attempts
+			mu.Unlock()
+			return nil
+		})
+
+	_, err := q.Push(ctx, "o1", orderTimeout{OrderId: 1}, 0)
+	assert.Nil(t, err)
+	_, err = q.Push(ctx, "o2", orderTimeout{OrderId: 2}, 0)
+	assert.Nil(t, err)
+	_, err = q.Push(ctx, "o3", orderTimeout{OrderId: 3}, time.Hour)
+	assert.Nil(t, err)
+	id, err := q.Push(ctx, "", orderTimeout{OrderId: 4}, 0)
+	assert.Nil(t, err)
+
+	ok, err := q.Cancel(ctx, id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = q.Cancel(ctx, id)
+	assert.False(t, ok)
+
+	go q.Start()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(done) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+	q.Stop()
+
+	assert.Equal(t, int64(1), done[1])
+	assert.Equal(t, int64(2), done[2])
+
+	// 未到期的任务还在
+	n, err := Get("delay").ZCard(ctx, "delay:{order}:zset").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestDelayQueueRePushDuringProcess(t *testing.T) {
+	setupRedis(t, "delay")
+	ctx := context.Background()
+
+	var q *DelayQueue[orderTimeout]
+	handled := make(chan DelayJob[orderTimeout], 1)
+	q = NewDelayQueue[orderTimeout]("delay", "repush").
+		SetPollInterval(20 * time.Millisecond).
+		Handle(func(ctx context.Context, job DelayJob[orderTimeout]) error {
+			// 处理期间同一个 id 重新 Push, 处理完成不能删除新的任务
+			if job.Payload.OrderId == 1 {
+				_, err := q.Push(ctx, job.Id, orderTimeout{OrderId: 2}, time.Hour)
+				assert.Nil(t, err)
+			}
+			handled <- job
+			return nil
+		})
+
+	_, err := q.Push(ctx, "o1", orderTimeout{OrderId: 1}, 0)
+	assert.Nil(t, err)
+
+	go q.Start()
+	select {
+	case job := <-handled:
+		assert.Equal(t, int64(1), job.Payload.OrderId)
+	case <-time.After(3 * time.Second):
+		t.Fatal("job not handled")
+	}
+	q.Stop()
+
+	client := Get("delay")
+	data, err := client.HGet(ctx, "delay:{repush}:data", "o1").Result()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"OrderId":2}`, data)
+	score, err := client.ZScore(ctx, "delay:{repush}:zset", "o1").Result()
+	assert.Nil(t, err)
+	assert.Greater(t, score, float64(time.Now().Add(50*time.Minute).UnixMilli()))
+
+	// 分数相同但是投递次数不同, 说明任务已经被重新 Push 或者取走, 不删除
+	ok, err := q.ack(ctx, DelayJob[orderTimeout]{Id: "o1", Attempts: 1, lease: int64(score)})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
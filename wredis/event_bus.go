@@ -0,0 +1,152 @@
+package wredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dawnco/cool/funcs"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var ErrEventBusClosed = errors.New("event bus closed")
+
+type eventHandler func(ctx context.Context, payload []byte)
+
+// EventBus 基于 redis pub/sub 的事件总线, 事件使用 json 编码
+// 连接断开后自动重连并重新订阅, 同一个 topic 的处理函数按顺序执行
+type EventBus struct {
+	client redis.UniversalClient
+
+	mu       sync.RWMutex
+	handlers map[string][]eventHandler
+	pubsub   *redis.PubSub
+	closed   bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEventBus name 是 Init 的配置名称
+func NewEventBus(name string) *EventBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventBus{
+		client:   Get(name),
+		handlers: make(map[string][]eventHandler),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Subscribe 订阅 topic, 收到的事件解码成 T 后调用 handler
+// handler panic 不会影响其他处理函数
+func Subscribe[T any](bus *EventBus, topic string, handler func(ctx context.Context, event T)) error {
+	return bus.subscribe(topic, func(ctx context.Context, payload []byte) {
+		var event T
+		if err := json.Unmarshal(payload, &event); err != nil {
+			logx.Errorf("event bus topic %s 事件解码失败 %s", topic, err.Error())
+			return
+		}
+		handler(ctx, event)
+	})
+}
+
+// Publish 发布事件
+func (b *EventBus) Publish(ctx context.Context, topic string, event any) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, topic, payload).Err()
+}
+
+// Close 取消所有订阅, 等待处理中的事件完成
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	pubsub := b.pubsub
+	b.mu.Unlock()
+
+	b.cancel()
+	var err error
+	if pubsub != nil {
+		err = pubsub.Close()
+	}
+	b.wg.Wait()
+	return err
+}
+
+func (b *EventBus) subscribe(topic string, handler eventHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrEventBusClosed
+	}
+
+	_, exists := b.handlers[topic]
+	b.handlers[topic] = append(b.handlers[topic], handler)
+	if exists {
+		return nil
+	}
+
+	// 第一次订阅时创建连接并开始接收
+	if b.pubsub == nil {
+		b.pubsub = b.client.Subscribe(b.ctx, topic)
+		b.wg.Add(1)
+		go b.receive(b.pubsub)
+		return nil
+	}
+	return b.pubsub.Subscribe(b.ctx, topic)
+}
+
+func (b *EventBus) receive(pubsub *redis.PubSub) {
+	defer b.wg.Done()
+
+	backoff := 100 * time.Millisecond
+	for {
+		msg, err := pubsub.ReceiveMessage(b.ctx)
+		if err != nil {
+			if b.ctx.Err() != nil || errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			// 下一次接收时 go-redis 会重连并重新订阅所有 topic
+			logx.Errorf("event bus 接收失败 %s 后重试 %s", backoff, err.Error())
+			timer := time.NewTimer(backoff)
+			select {
+			case <-b.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, 5*time.Second)
+			continue
+		}
+		backoff = 100 * time.Millisecond
+
+		b.mu.RLock()
+		handlers := b.handlers[msg.Channel]
+		b.mu.RUnlock()
+
+		for _, handler := range handlers {
+			b.dispatch(msg.Channel, handler, []byte(msg.Payload))
+		}
+	}
+}
+
+func (b *EventBus) dispatch(topic string, handler eventHandler, payload []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			logx.Errorf("event bus topic %s 处理函数 panic %v\n%s", topic, r, funcs.StackTrace())
+		}
+	}()
+	handler(context.WithoutCancel(b.ctx), payload)
+}
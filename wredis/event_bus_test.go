@@ -0,0 +1,46 @@
+package wredis
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type configChanged struct {
+	Key string
+}
+
+func TestEventBus(t *testing.T) {
+	m := setupRedis(t, "bus")
+	ctx := context.Background()
+
+	bus := NewEventBus("bus")
+
+	var got atomic.Value
+	var panics int32
+	assert.Nil(t, Subscribe(bus, "config", func(ctx context.Context, e configChanged) {
+		atomic.AddInt32(&panics, 1)
+		panic("handler panic")
+	}))
+	assert.Nil(t, Subscribe(bus, "config", func(ctx context.Context, e configChanged) {
+		got.Store(e.Key)
+	}))
+
+	publish := func(key string) bool {
+		assert.Nil(t, bus.Publish(ctx, "config", configChanged{Key: key}))
+		return got.Load() == key
+	}
+
+	assert.Eventually(t, func() bool { return publish("a") }, 2*time.Second, 20*time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&panics) > 0)
+
+	// 连接断开后自动重新订阅
+	m.Restart()
+	assert.Eventually(t, func() bool { return publish("b") }, 5*time.Second, 50*time.Millisecond)
+
+	assert.Nil(t, bus.Close())
+	assert.ErrorIs(t, Subscribe(bus, "other", func(ctx context.Context, e configChanged) {}), ErrEventBusClosed)
+}
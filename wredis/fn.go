@@ -1,6 +1,7 @@
 package wredis
 
 import (
+	"crypto/tls"
 	"fmt"
 
 	"github.com/redis/go-redis/v9"
@@ -11,22 +12,68 @@ import (
 var instance = sync.Map{}
 
 // Init 初始化
-// name 配置名称, 后面通过 GetClient 获取这个配置的客户端
-// cfg 配置参数
+// name 配置名称, 后面通过 Get 获取这个配置的客户端
+// cfg 配置参数, cfg.Mode 支持 single sentinel cluster
 func Init(name string, cfg Cfg) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password: cfg.Password,
-		DB:       cfg.Db,
-	})
+	client := newClient(cfg)
+	if cfg.KeyPrefix != "" {
+		client.AddHook(newPrefixHook(cfg.KeyPrefix))
+	}
+	if hook := newMetricsHook(name, cfg); hook.enabled() {
+		client.AddHook(hook)
+	}
 	instance.Store(name, client)
-
 }
 
-func Get(name string) *redis.Client {
+// Get 获取客户端, 不同部署模式都返回 redis.UniversalClient
+func Get(name string) redis.UniversalClient {
 	conn, ok := instance.Load(name)
 	if !ok {
 		panic(fmt.Errorf("redis connection %s not found", name))
 	}
-	return conn.(*redis.Client)
+	return conn.(redis.UniversalClient)
+}
+
+func newClient(cfg Cfg) redis.UniversalClient {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		DB:               cfg.Db,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelPassword: cfg.SentinelPassword,
+		MasterName:       cfg.MasterName,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		PoolTimeout:      cfg.PoolTimeout,
+	}
+
+	if cfg.Tls {
+		opts.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: cfg.TlsSkipVerify,
+		}
+	}
+
+	switch cfg.Mode {
+	case ModeSingle, "":
+		if len(opts.Addrs) == 0 {
+			opts.Addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+		}
+		return redis.NewClient(opts.Simple())
+	case ModeSentinel:
+		if cfg.MasterName == "" || len(cfg.Addrs) == 0 {
+			panic(fmt.Errorf("redis sentinel mode requires MasterName and Addrs"))
+		}
+		return redis.NewFailoverClient(opts.Failover())
+	case ModeCluster:
+		if len(cfg.Addrs) == 0 {
+			panic(fmt.Errorf("redis cluster mode requires Addrs"))
+		}
+		return redis.NewClusterClient(opts.Cluster())
+	default:
+		panic(fmt.Errorf("redis mode %s not supported", cfg.Mode))
+	}
 }
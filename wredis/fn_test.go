@@ -0,0 +1,30 @@
+package wredis
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// setupRedis 启动内存 redis 并初始化名称为 name 的客户端
+func setupRedis(t *testing.T, name string) *miniredis.Miniredis {
+	return setupRedisCfg(t, name, Cfg{})
+}
+
+// setupRedisCfg 使用 cfg 的其他配置, 地址和 db 使用内存 redis 的
+func setupRedisCfg(t *testing.T, name string, cfg Cfg) *miniredis.Miniredis {
+	m := miniredis.RunT(t)
+	port, err := strconv.Atoi(m.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Host = m.Host()
+	cfg.Port = port
+	cfg.Db = 0
+	Init(name, cfg)
+	t.Cleanup(func() {
+		_ = Get(name).Close()
+	})
+	return m
+}
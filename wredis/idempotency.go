@@ -0,0 +1,184 @@
+package wredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrIdempotencyInProgress = errors.New("idempotency key is processing")
+	ErrIdempotencyMismatch   = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyNotOwner   = errors.New("idempotency key is not reserved by this token")
+)
+
+// 只有占用 key 时的 token 才能保存响应或者释放
+// 处理超过 lockTTL 后 key 可能已经被其他请求占用
+var (
+	idempotencyCompleteScript = redis.NewScript(`
+local v = redis.call('get', KEYS[1])
+if not v then
+	return 0
+end
+local ok, r = pcall(cjson.decode, v)
+if not ok or r.State ~= 'processing' or r.Token ~= ARGV[1] then
+	return 0
+end
+redis.call('set', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1`)
+
+	idempotencyReleaseScript = redis.NewScript(`
+local v = redis.call('get', KEYS[1])
+if not v then
+	return 0
+end
+local ok, r = pcall(cjson.decode, v)
+if not ok or r.State ~= 'processing' or r.Token ~= ARGV[1] then
+	return 0
+end
+return redis.call('del', KEYS[1])`)
+)
+
+const (
+	idempotencyProcessing = "processing"
+	idempotencyDone       = "done"
+)
+
+// IdempotentResponse 保存的响应, 重试时原样返回
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type idempotencyRecord struct {
+	State       string
+	Fingerprint string
+	Token       string              `json:",omitempty"` // 处理中时占用者的 token
+	Response    *IdempotentResponse `json:",omitempty"`
+}
+
+// IdempotencyStore 幂等 key 存储
+// 第一次请求占用 key 并标记为处理中, 完成后保存响应, 之后相同 key 的请求直接返回保存的响应
+type IdempotencyStore struct {
+	client  redis.UniversalClient
+	prefix  string
+	lockTTL time.Duration
+	ttl     time.Duration
+}
+
+// NewIdempotencyStore name 是 Init 的配置名称, prefix 是 redis key 前缀
+// 默认处理中状态保留 1 分钟, 响应保留 24 小时
+func NewIdempotencyStore(name, prefix string) *IdempotencyStore {
+	return &IdempotencyStore{
+		client:  Get(name),
+		prefix:  prefix,
+		lockTTL: time.Minute,
+		ttl:     24 * time.Hour,
+	}
+}
+
+// SetLockTTL 处理中状态的过期时间, 应大于请求的最长处理时间
+func (s *IdempotencyStore) SetLockTTL(d time.Duration) *IdempotencyStore {
+	s.lockTTL = d
+	return s
+}
+
+// SetTTL 响应保存的时间
+func (s *IdempotencyStore) SetTTL(d time.Duration) *IdempotencyStore {
+	s.ttl = d
+	return s
+}
+
+// Reserve 占用 key
+// 返回 nil, token, nil 表示第一次请求, 需要处理后用 token 调用 Complete 或 Release
+// 已经处理完成返回保存的响应, 处理中返回 ErrIdempotencyInProgress
+// fingerprint 是请求的摘要, 和第一次请求不同时返回 ErrIdempotencyMismatch
+func (s *IdempotencyStore) Reserve(ctx context.Context, key, fingerprint string) (*IdempotentResponse, string, error) {
+	token := newToken()
+	data, err := json.Marshal(idempotencyRecord{State: idempotencyProcessing, Fingerprint: fingerprint, Token: token})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 读取时 key 刚好过期 重试一次
+	for i := 0; i < 2; i++ {
+		ok, err := s.client.SetNX(ctx, s.key(key), data, s.lockTTL).Result()
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			return nil, token, nil
+		}
+
+		record, err := s.get(ctx, key)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		if record.Fingerprint != fingerprint {
+			return nil, "", ErrIdempotencyMismatch
+		}
+		if record.State != idempotencyDone || record.Response == nil {
+			return nil, "", ErrIdempotencyInProgress
+		}
+		return record.Response, "", nil
+	}
+	return nil, "", ErrIdempotencyInProgress
+}
+
+// Complete 保存响应, key 已经不是 token 占用的返回 ErrIdempotencyNotOwner
+func (s *IdempotencyStore) Complete(ctx context.Context, key, token, fingerprint string, resp *IdempotentResponse) error {
+	data, err := json.Marshal(idempotencyRecord{
+		State:       idempotencyDone,
+		Fingerprint: fingerprint,
+		Response:    resp,
+	})
+	if err != nil {
+		return err
+	}
+	ok, err := idempotencyCompleteScript.Run(ctx, s.client, []string{s.key(key)}, token, data, s.ttl.Milliseconds()).Bool()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIdempotencyNotOwner
+	}
+	return nil
+}
+
+// Release 释放 key, 处理失败时调用, 客户端可以用相同的 key 重试
+// key 已经不是 token 占用的返回 ErrIdempotencyNotOwner
+func (s *IdempotencyStore) Release(ctx context.Context, key, token string) error {
+	ok, err := idempotencyReleaseScript.Run(ctx, s.client, []string{s.key(key)}, token).Bool()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIdempotencyNotOwner
+	}
+	return nil
+}
+
+func (s *IdempotencyStore) get(ctx context.Context, key string) (*idempotencyRecord, error) {
+	data, err := s.client.Get(ctx, s.key(key)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *IdempotencyStore) key(key string) string {
+	return s.prefix + ":" + key
+}
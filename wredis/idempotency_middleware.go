@@ -0,0 +1,184 @@
+package wredis
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// 不保存的响应头, 重放时不能把第一次请求的 cookie 和连接相关的头返回给客户端
+var idempotencySkipHeaders = map[string]bool{
+	"Set-Cookie":          true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// IdempotencyScope 默认的幂等 key 作用域
+// 有 session 时按用户, 否则按 Authorization 请求头, 都没有时所有匿名请求共用
+func IdempotencyScope(r *http.Request) string {
+	if session, ok := SessionFromContext(r.Context()); ok && session.UserId() != "" {
+		return "user:" + session.UserId()
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		return "auth:" + hex.EncodeToString(sum[:])
+	}
+	return ""
+}
+
+// IdempotencyMiddleware go-zero 幂等中间件, 请求头带 Idempotency-Key 时生效
+// 处理中的重复请求返回 409, 相同 key 不同请求内容返回 422, 已完成的返回保存的响应
+// 响应状态码 >= 500 时不保存, 客户端可以重试
+// scope 返回调用方的标识, 不同调用方的相同 key 互不影响, nil 使用 IdempotencyScope
+func IdempotencyMiddleware(store *IdempotencyStore, scope KeyFunc) rest.Middleware {
+	if scope == nil {
+		scope = IdempotencyScope
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			idemKey := r.Header.Get(IdempotencyKeyHeader)
+			if idemKey == "" {
+				next(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := scope(r) + ":" + r.Method + ":" + r.URL.Path + ":" + idemKey
+			fingerprint := requestFingerprint(r, body)
+
+			resp, token, err := store.Reserve(ctx, key, fingerprint)
+			switch {
+			case errors.Is(err, ErrIdempotencyInProgress):
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			case errors.Is(err, ErrIdempotencyMismatch):
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			case err != nil:
+				// redis 不可用时不做幂等处理
+				logx.WithContext(ctx).Errorf("幂等 key %s 检查失败 %s", key, err.Error())
+				next(w, r)
+				return
+			case resp != nil:
+				replay(w, resp)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// handler panic 或者服务端错误 释放 key 允许重试
+				if err := store.Release(context.WithoutCancel(ctx), key, token); err != nil {
+					logx.WithContext(ctx).Errorf("幂等 key %s 释放失败 %s", key, err.Error())
+				}
+			}()
+
+			next(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			completed = true
+			err = store.Complete(context.WithoutCancel(ctx), key, token, fingerprint, &IdempotentResponse{
+				Status: rec.status,
+				Header: replayHeader(rec.Header()),
+				Body:   rec.body.Bytes(),
+			})
+			if err != nil {
+				logx.WithContext(ctx).Errorf("幂等 key %s 保存响应失败 %s", key, err.Error())
+			}
+		}
+	}
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayHeader 去掉不能重放的响应头, 包括 Connection 里列出的
+func replayHeader(header http.Header) http.Header {
+	skip := map[string]bool{}
+	for _, v := range header.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			skip[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	result := http.Header{}
+	for k, v := range header {
+		if idempotencySkipHeaders[k] || skip[k] {
+			continue
+		}
+		result[k] = append([]string(nil), v...)
+	}
+	return result
+}
+
+func replay(w http.ResponseWriter, resp *IdempotentResponse) {
+	// 之前保存的响应可能还有不能重放的头
+	for k, v := range replayHeader(resp.Header) {
+		w.Header()[k] = v
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// responseRecorder 记录响应的同时写给客户端
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
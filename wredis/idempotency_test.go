@@ -0,0 +1,118 @@
+package wredis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	m := setupRedis(t, "idem")
+	ctx := context.Background()
+
+	s := NewIdempotencyStore("idem", "idem").SetLockTTL(time.Second)
+
+	resp, token, err := s.Reserve(ctx, "k1", "fp")
+	assert.Nil(t, err)
+	assert.Nil(t, resp)
+	assert.NotEmpty(t, token)
+
+	_, _, err = s.Reserve(ctx, "k1", "fp")
+	assert.ErrorIs(t, err, ErrIdempotencyInProgress)
+
+	// 其他请求不能保存或者释放
+	assert.ErrorIs(t, s.Complete(ctx, "k1", "other", "fp", &IdempotentResponse{Status: 500}), ErrIdempotencyNotOwner)
+	assert.ErrorIs(t, s.Release(ctx, "k1", "other"), ErrIdempotencyNotOwner)
+
+	assert.Nil(t, s.Complete(ctx, "k1", token, "fp", &IdempotentResponse{Status: 201, Body: []byte("ok")}))
+	resp, _, err = s.Reserve(ctx, "k1", "fp")
+	assert.Nil(t, err)
+	assert.Equal(t, 201, resp.Status)
+
+	_, _, err = s.Reserve(ctx, "k1", "other")
+	assert.ErrorIs(t, err, ErrIdempotencyMismatch)
+
+	// 完成后不能再释放
+	assert.ErrorIs(t, s.Release(ctx, "k1", token), ErrIdempotencyNotOwner)
+
+	// 处理超时 key 被其他请求占用, 之前的请求不能覆盖
+	_, token1, err := s.Reserve(ctx, "k2", "fp")
+	assert.Nil(t, err)
+	m.FastForward(2 * time.Second)
+	_, token2, err := s.Reserve(ctx, "k2", "fp")
+	assert.Nil(t, err)
+	assert.ErrorIs(t, s.Complete(ctx, "k2", token1, "fp", &IdempotentResponse{Status: 201}), ErrIdempotencyNotOwner)
+	assert.ErrorIs(t, s.Release(ctx, "k2", token1), ErrIdempotencyNotOwner)
+	assert.Nil(t, s.Release(ctx, "k2", token2))
+	resp, _, err = s.Reserve(ctx, "k2", "other")
+	assert.Nil(t, err)
+	assert.Nil(t, resp)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	setupRedis(t, "idem")
+
+	var calls int32
+	handler := IdempotencyMiddleware(NewIdempotencyStore("idem", "idem"), nil)(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if strings.Contains(r.URL.Path, "fail") && n == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Order", "1001")
+		w.Header().Set("Set-Cookie", "sid=secret")
+		w.Header().Set("Connection", "X-Hop")
+		w.Header().Set("X-Hop", "1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1001}`))
+	})
+
+	auth := "Bearer a"
+	do := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := do("/order", "k1", "a")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "", w.Header().Get(IdempotencyReplayedHeader))
+
+	// 重试返回保存的响应 不再执行
+	w = do("/order", "k1", "a")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"id":1001}`, w.Body.String())
+	assert.Equal(t, "1001", w.Header().Get("X-Order"))
+	assert.Equal(t, "true", w.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	// cookie 和连接相关的头不重放
+	assert.Equal(t, "", w.Header().Get("Set-Cookie"))
+	assert.Equal(t, "", w.Header().Get("Connection"))
+	assert.Equal(t, "", w.Header().Get("X-Hop"))
+
+	// 其他调用方相同的 key 不会拿到第一个调用方的响应
+	auth = "Bearer b"
+	w = do("/order", "k1", "a")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "", w.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	auth = "Bearer a"
+
+	w = do("/order", "k1", "b")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// 服务端错误不保存 可以重试
+	atomic.StoreInt32(&calls, 0)
+	assert.Equal(t, http.StatusInternalServerError, do("/fail", "k2", "a").Code)
+	assert.Equal(t, http.StatusCreated, do("/fail", "k2", "a").Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,171 @@
+package wredis
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dawnco/cool/funcs"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// key 不存在时占用, 是自己时续期
+var leaderAcquireScript = redis.NewScript(`
+local v = redis.call('get', KEYS[1])
+if v == false then
+	redis.call('set', KEYS[1], ARGV[1], 'px', ARGV[2])
+	return 1
+end
+if v == ARGV[1] then
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var leaderReleaseScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// LeaderElector 基于租约的选主, 实现了 go-zero service.Service
+// 每隔 ttl/3 续期一次, 续期失败或者 redis 出错时立即放弃 leader
+type LeaderElector struct {
+	client    redis.UniversalClient
+	key       string
+	id        string
+	ttl       time.Duration
+	interval  time.Duration
+	leader    atomic.Bool
+	onElected func()
+	onRevoked func()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewLeaderElector name 是 Init 的配置名称, key 是租约的 redis key
+// 默认租约 15 秒, 实例标识是 主机名-随机串
+func NewLeaderElector(name, key string) *LeaderElector {
+	host, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LeaderElector{
+		client:   Get(name),
+		key:      key,
+		id:       host + "-" + newToken()[:8],
+		ttl:      15 * time.Second,
+		interval: 5 * time.Second,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// SetTTL 租约时间, 续期间隔是 ttl/3
+func (e *LeaderElector) SetTTL(ttl time.Duration) *LeaderElector {
+	e.ttl = ttl
+	e.interval = ttl / 3
+	return e
+}
+
+// SetId 实例标识
+func (e *LeaderElector) SetId(id string) *LeaderElector {
+	e.id = id
+	return e
+}
+
+// OnElected 成为 leader 时调用, 不要阻塞
+func (e *LeaderElector) OnElected(fn func()) *LeaderElector {
+	e.onElected = fn
+	return e
+}
+
+// OnRevoked 失去 leader 时调用, 不要阻塞
+func (e *LeaderElector) OnRevoked(fn func()) *LeaderElector {
+	e.onRevoked = fn
+	return e
+}
+
+func (e *LeaderElector) Id() string {
+	return e.id
+}
+
+// IsLeader 当前是否是 leader
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start 开始选主, 阻塞直到 Stop
+func (e *LeaderElector) Start() {
+	e.wg.Add(1)
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.campaign()
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 停止选主, 是 leader 时释放租约, 其他实例可以马上接替
+func (e *LeaderElector) Stop() {
+	e.cancel()
+	e.wg.Wait()
+
+	if !e.leader.Load() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := leaderReleaseScript.Run(ctx, e.client, []string{e.key}, e.id).Err(); err != nil {
+		logx.Errorf("leader %s 释放租约失败 %s", e.key, err.Error())
+	}
+	e.setLeader(false)
+}
+
+func (e *LeaderElector) campaign() {
+	ok, err := leaderAcquireScript.Run(e.ctx, e.client, []string{e.key}, e.id, e.ttl.Milliseconds()).Bool()
+	if err != nil {
+		if e.ctx.Err() != nil {
+			return
+		}
+		logx.Errorf("leader %s 续期失败 %s", e.key, err.Error())
+	}
+	e.setLeader(ok && err == nil)
+}
+
+func (e *LeaderElector) setLeader(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+	if leader {
+		logx.Infof("leader %s 当选 %s", e.key, e.id)
+		e.callback(e.onElected)
+	} else {
+		logx.Infof("leader %s 卸任 %s", e.key, e.id)
+		e.callback(e.onRevoked)
+	}
+}
+
+func (e *LeaderElector) callback(fn func()) {
+	if fn == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logx.Errorf("leader %s 回调 panic %v\n%s", e.key, r, funcs.StackTrace())
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,36 @@
+package wredis
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderElector(t *testing.T) {
+	setupRedis(t, "leader")
+
+	var elected, revoked int32
+	a := NewLeaderElector("leader", "job:leader").SetId("a").SetTTL(300 * time.Millisecond).
+		OnElected(func() { atomic.AddInt32(&elected, 1) }).
+		OnRevoked(func() { atomic.AddInt32(&revoked, 1) })
+	b := NewLeaderElector("leader", "job:leader").SetId("b").SetTTL(300 * time.Millisecond)
+
+	go a.Start()
+	assert.Eventually(t, a.IsLeader, time.Second, 10*time.Millisecond)
+	go b.Start()
+	defer b.Stop()
+
+	// 续期后仍然是 a
+	time.Sleep(500 * time.Millisecond)
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	// a 退出后 b 接替
+	a.Stop()
+	assert.False(t, a.IsLeader())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&elected))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&revoked))
+	assert.Eventually(t, b.IsLeader, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,143 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderboardEntry 排行榜的一项
+type LeaderboardEntry struct {
+	Member string
+	Score  float64
+	Rank   int64 // 从 1 开始
+}
+
+// Leaderboard 基于 zset 的排行榜, 默认分数高的排前面
+type Leaderboard struct {
+	client redis.UniversalClient
+	key    string
+	asc    bool
+}
+
+// NewLeaderboard name 是 Init 的配置名称, key 是排行榜的 redis key
+func NewLeaderboard(name, key string) *Leaderboard {
+	return &Leaderboard{
+		client: Get(name),
+		key:    key,
+	}
+}
+
+// SetAsc 分数低的排前面, 比如用时排行
+func (l *Leaderboard) SetAsc(asc bool) *Leaderboard {
+	l.asc = asc
+	return l
+}
+
+// Add 设置分数
+func (l *Leaderboard) Add(ctx context.Context, member string, score float64) error {
+	return l.client.ZAdd(ctx, l.key, redis.Z{Score: score, Member: member}).Err()
+}
+
+// Incr 增加分数, 返回新的分数
+func (l *Leaderboard) Incr(ctx context.Context, member string, delta float64) (float64, error) {
+	return l.client.ZIncrBy(ctx, l.key, delta, member).Result()
+}
+
+// Remove 移除成员
+func (l *Leaderboard) Remove(ctx context.Context, members ...string) error {
+	args := make([]any, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	return l.client.ZRem(ctx, l.key, args...).Err()
+}
+
+// Count 成员数量
+func (l *Leaderboard) Count(ctx context.Context) (int64, error) {
+	return l.client.ZCard(ctx, l.key).Result()
+}
+
+// Rank 成员的排名和分数, 不在榜上第二个返回值为 false
+func (l *Leaderboard) Rank(ctx context.Context, member string) (LeaderboardEntry, bool, error) {
+	var rankCmd *redis.IntCmd
+	var scoreCmd *redis.FloatCmd
+	_, err := l.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if l.asc {
+			rankCmd = pipe.ZRank(ctx, l.key, member)
+		} else {
+			rankCmd = pipe.ZRevRank(ctx, l.key, member)
+		}
+		scoreCmd = pipe.ZScore(ctx, l.key, member)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return LeaderboardEntry{}, false, nil
+	}
+	if err != nil {
+		return LeaderboardEntry{}, false, err
+	}
+
+	return LeaderboardEntry{
+		Member: member,
+		Score:  scoreCmd.Val(),
+		Rank:   rankCmd.Val() + 1,
+	}, true, nil
+}
+
+// Top 前 n 名
+func (l *Leaderboard) Top(ctx context.Context, n int64) ([]LeaderboardEntry, error) {
+	// stop 为 -1 会返回整个榜单
+	if n <= 0 {
+		return nil, nil
+	}
+	return l.rangeByRank(ctx, 0, n-1)
+}
+
+// Page 分页列表, page 从 1 开始
+func (l *Leaderboard) Page(ctx context.Context, page, size int64) ([]LeaderboardEntry, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * size
+	return l.rangeByRank(ctx, start, start+size-1)
+}
+
+// AroundMe 成员前后各 n 名, 包含成员自己, 不在榜上返回空
+func (l *Leaderboard) AroundMe(ctx context.Context, member string, n int64) ([]LeaderboardEntry, error) {
+	me, ok, err := l.Rank(ctx, member)
+	if err != nil || !ok {
+		return nil, err
+	}
+	start := max(me.Rank-1-n, 0)
+	return l.rangeByRank(ctx, start, me.Rank-1+n)
+}
+
+// rangeByRank start stop 是从 0 开始的排名
+func (l *Leaderboard) rangeByRank(ctx context.Context, start, stop int64) ([]LeaderboardEntry, error) {
+	var zs []redis.Z
+	var err error
+	if l.asc {
+		zs, err = l.client.ZRangeWithScores(ctx, l.key, start, stop).Result()
+	} else {
+		zs, err = l.client.ZRevRangeWithScores(ctx, l.key, start, stop).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LeaderboardEntry, len(zs))
+	for i, z := range zs {
+		member, _ := z.Member.(string)
+		entries[i] = LeaderboardEntry{
+			Member: member,
+			Score:  z.Score,
+			Rank:   start + int64(i) + 1,
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+package wredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	setupRedis(t, "rank")
+	ctx := context.Background()
+
+	lb := NewLeaderboard("rank", "lb:score")
+	for i := 1; i <= 10; i++ {
+		assert.Nil(t, lb.Add(ctx, fmt.Sprintf("u%d", i), float64(i*10)))
+	}
+
+	score, err := lb.Incr(ctx, "u1", 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1010), score)
+
+	top, err := lb.Top(ctx, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"u1", "u10", "u9"}, members(top))
+	assert.Equal(t, int64(3), top[2].Rank)
+
+	me, ok, err := lb.Rank(ctx, "u8")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), me.Rank)
+	assert.Equal(t, float64(80), me.Score)
+
+	_, ok, err = lb.Rank(ctx, "nobody")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	around, err := lb.AroundMe(ctx, "u8", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"u9", "u8", "u7"}, members(around))
+
+	page, err := lb.Page(ctx, 2, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"u7", "u6", "u5", "u4"}, members(page))
+	assert.Equal(t, int64(5), page[0].Rank)
+
+	// n 或 size 为 0 时不能返回整个榜单
+	top, err = lb.Top(ctx, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, top)
+	page, err = lb.Page(ctx, 1, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, page)
+
+	asc := NewLeaderboard("rank", "lb:score").SetAsc(true)
+	top, err = asc.Top(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"u2"}, members(top))
+
+	assert.Nil(t, lb.Remove(ctx, "u1", "u2"))
+	n, err := lb.Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), n)
+}
+
+func members(entries []LeaderboardEntry) []string {
+	result := make([]string, len(entries))
+	for i, e := range entries {
+		result[i] = e.Member
+	}
+	return result
+}
+
+func TestWindowCounter(t *testing.T) {
+	setupRedis(t, "rank")
+	ctx := context.Background()
+
+	c := NewWindowCounter("rank", "wc", time.Minute, time.Hour)
+	assert.Nil(t, c.Incr(ctx, "login", 2))
+	assert.Nil(t, c.Incr(ctx, "login", 3))
+
+	n, err := c.Count(ctx, "login", 10*time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n)
+
+	n, err = c.Count(ctx, "other", 10*time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+
+	// 小于 1 秒的桶
+	fast := NewWindowCounter("rank", "wc", 100*time.Millisecond, time.Second)
+	assert.Nil(t, fast.Incr(ctx, "api", 1))
+	n, err = fast.Count(ctx, "api", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	assert.Panics(t, func() { NewWindowCounter("rank", "wc", time.Microsecond, time.Second) })
+}
+
+func TestUniqueCounter(t *testing.T) {
+	setupRedis(t, "rank")
+	ctx := context.Background()
+
+	c := NewUniqueCounter("rank", "uv", time.Hour)
+	assert.Nil(t, c.Add(ctx, "d1", "a", "b", "c", "a"))
+	assert.Nil(t, c.Add(ctx, "d2", "c", "d"))
+
+	n, err := c.Count(ctx, "d1")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	assert.Nil(t, c.Merge(ctx, "week", "d1", "d2"))
+	n, err = c.Count(ctx, "week")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), n)
+}
@@ -0,0 +1,165 @@
+package wredis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 限流器的时间使用调用方的时钟, 多个实例之间需要保证时间同步
+
+var (
+	fixedWindowScript = redis.NewScript(`
+local n = redis.call('incr', KEYS[1])
+local ttl = redis.call('pttl', KEYS[1])
+if ttl < 0 then
+	redis.call('pexpire', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {n, ttl}`)
+
+	slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('zremrangebyscore', KEYS[1], '-inf', now - window)
+local n = redis.call('zcard', KEYS[1])
+if n < limit then
+	redis.call('zadd', KEYS[1], now, ARGV[4])
+	redis.call('pexpire', KEYS[1], window)
+	return {1, limit - n - 1, 0}
+end
+local first = redis.call('zrange', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(first[2]) + window - now}`)
+
+	tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('hmget', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('hmset', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('pexpire', KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, math.floor(tokens), retry}`)
+)
+
+// LimitResult 限流结果
+type LimitResult struct {
+	Allowed    bool          // 是否允许
+	Remaining  int64         // 剩余次数
+	RetryAfter time.Duration // 不允许时 多久后可以重试
+}
+
+// Limiter 限流器 key 是限流对象 比如用户ID IP
+type Limiter interface {
+	Allow(ctx context.Context, key string) (LimitResult, error)
+}
+
+// FixedWindowLimiter 固定窗口 每个窗口最多 limit 次
+type FixedWindowLimiter struct {
+	client redis.UniversalClient
+	prefix string
+	limit  int64
+	window time.Duration
+}
+
+// NewFixedWindowLimiter name 是 Init 的配置名称, prefix 是 redis key 前缀
+func NewFixedWindowLimiter(name, prefix string, limit int64, window time.Duration) *FixedWindowLimiter {
+	return &FixedWindowLimiter{
+		client: Get(name),
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (l *FixedWindowLimiter) Allow(ctx context.Context, key string) (LimitResult, error) {
+	vals, err := fixedWindowScript.Run(ctx, l.client, []string{l.prefix + ":" + key}, l.window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return LimitResult{}, err
+	}
+
+	n, ttl := vals[0], vals[1]
+	if n > l.limit {
+		return LimitResult{RetryAfter: time.Duration(ttl) * time.Millisecond}, nil
+	}
+	return LimitResult{Allowed: true, Remaining: l.limit - n}, nil
+}
+
+// SlidingWindowLimiter 滑动窗口日志 任意 window 时间内最多 limit 次
+// 每次请求占用 zset 一个成员, 适合 limit 不大的场景
+type SlidingWindowLimiter struct {
+	client redis.UniversalClient
+	prefix string
+	limit  int64
+	window time.Duration
+}
+
+func NewSlidingWindowLimiter(name, prefix string, limit int64, window time.Duration) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		client: Get(name),
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string) (LimitResult, error) {
+	now := time.Now().UnixMilli()
+	vals, err := slidingWindowScript.Run(ctx, l.client, []string{l.prefix + ":" + key},
+		now, l.window.Milliseconds(), l.limit, strconv.FormatInt(now, 10)+"-"+newToken()).Int64Slice()
+	if err != nil {
+		return LimitResult{}, err
+	}
+
+	return LimitResult{
+		Allowed:    vals[0] == 1,
+		Remaining:  vals[1],
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
+
+// TokenBucketLimiter 令牌桶 每秒补充 rate 个令牌, 最多 burst 个
+type TokenBucketLimiter struct {
+	client redis.UniversalClient
+	prefix string
+	rate   float64
+	burst  int64
+}
+
+func NewTokenBucketLimiter(name, prefix string, rate float64, burst int64) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		client: Get(name),
+		prefix: prefix,
+		rate:   rate,
+		burst:  burst,
+	}
+}
+
+func (l *TokenBucketLimiter) Allow(ctx context.Context, key string) (LimitResult, error) {
+	// 脚本里使用每毫秒的速率
+	perMs := strconv.FormatFloat(l.rate/1000, 'f', -1, 64)
+	vals, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + ":" + key},
+		perMs, l.burst, time.Now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return LimitResult{}, err
+	}
+
+	return LimitResult{
+		Allowed:    vals[0] == 1,
+		Remaining:  vals[1],
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
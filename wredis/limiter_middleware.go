@@ -0,0 +1,120 @@
+package wredis
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+// KeyFunc 从请求中获取限流的 key, 返回空字符串不限流
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP 按连接的客户端 IP 限流, 不使用 X-Forwarded-For, 客户端可以伪造
+// 部署在反向代理后面时使用 KeyByTrustedIP
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+// KeyByTrustedIP 按客户端 IP 限流, proxies 是可信代理的 IP 或 CIDR, 比如 10.0.0.0/8
+// 连接来自可信代理时, 从右往左取 X-Forwarded-For 中第一个不是可信代理的地址
+// proxies 格式错误时 panic
+func KeyByTrustedIP(proxies ...string) KeyFunc {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			panic("wredis: invalid trusted proxy " + p)
+		}
+		nets = append(nets, n)
+	}
+
+	trusted := func(s string) bool {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return false
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(r *http.Request) string {
+		ip := remoteIP(r)
+		if !trusted(ip) {
+			return ip
+		}
+		// 多个 X-Forwarded-For 头按顺序拼接, 最右边是最后一个代理添加的
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if !trusted(hop) {
+				return hop
+			}
+			ip = hop
+		}
+		// 全部是可信代理, 使用最左边的
+		return ip
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader 按请求头限流 比如 X-Api-Key
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateLimitMiddleware go-zero 限流中间件, 超过限制返回 429
+// redis 出错时不限流, 只记录日志
+func RateLimitMiddleware(limiter Limiter, keyFunc KeyFunc) rest.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next(w, r)
+				return
+			}
+
+			res, err := limiter.Allow(r.Context(), key)
+			if err != nil {
+				logx.WithContext(r.Context()).Errorf("限流检查失败 %s", err.Error())
+				next(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package wredis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWindowLimiter(t *testing.T) {
+	m := setupRedis(t, "limiter")
+	ctx := context.Background()
+
+	l := NewFixedWindowLimiter("limiter", "rl:fixed", 2, time.Minute)
+
+	res, err := l.Allow(ctx, "u1")
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(1), res.Remaining)
+
+	res, _ = l.Allow(ctx, "u1")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+
+	res, _ = l.Allow(ctx, "u1")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Minute, res.RetryAfter)
+
+	// 不同 key 互不影响
+	res, _ = l.Allow(ctx, "u2")
+	assert.True(t, res.Allowed)
+
+	m.FastForward(time.Minute)
+	res, _ = l.Allow(ctx, "u1")
+	assert.True(t, res.Allowed)
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	setupRedis(t, "limiter")
+	ctx := context.Background()
+
+	l := NewSlidingWindowLimiter("limiter", "rl:sliding", 2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		res, err := l.Allow(ctx, "u1")
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	res, err := l.Allow(ctx, "u1")
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, res.RetryAfter > 0 && res.RetryAfter <= time.Minute)
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	setupRedis(t, "limiter")
+	ctx := context.Background()
+
+	l := NewTokenBucketLimiter("limiter", "rl:bucket", 1, 3)
+
+	for i := 0; i < 3; i++ {
+		res, err := l.Allow(ctx, "u1")
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(2-i), res.Remaining)
+	}
+
+	res, err := l.Allow(ctx, "u1")
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, res.RetryAfter > 0 && res.RetryAfter <= time.Second)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	setupRedis(t, "limiter")
+
+	l := NewFixedWindowLimiter("limiter", "rl:mw", 1, time.Minute)
+	handler := RateLimitMiddleware(l, KeyByHeader("X-Api-Key"))(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", "k1")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do().Code)
+	w := do()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestKeyByIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "1.2.3.4:5678"
+	req.Header.Set("X-Forwarded-For", "9.9.9.9")
+
+	// 默认不信任 X-Forwarded-For
+	assert.Equal(t, "1.2.3.4", KeyByIP(req))
+
+	keyFunc := KeyByTrustedIP("10.0.0.0/8", "192.168.1.1")
+	// 连接不是来自可信代理, 忽略 X-Forwarded-For
+	assert.Equal(t, "1.2.3.4", keyFunc(req))
+
+	// 客户端伪造的第一个地址不使用, 取最右边不是可信代理的地址
+	req.RemoteAddr = "10.0.0.2:5678"
+	req.Header.Set("X-Forwarded-For", "6.6.6.6, 1.2.3.4, 192.168.1.1")
+	assert.Equal(t, "1.2.3.4", keyFunc(req))
+
+	req.Header.Set("X-Forwarded-For", "10.1.1.1")
+	req.Header.Add("X-Forwarded-For", "192.168.1.1")
+	assert.Equal(t, "10.1.1.1", keyFunc(req))
+
+	req.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "10.0.0.2", keyFunc(req))
+
+	assert.Panics(t, func() { KeyByTrustedIP("not-an-ip") })
+}
@@ -0,0 +1,242 @@
+package wredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var ErrLockNotHeld = errors.New("redis lock not held")
+
+// 锁是一个 hash, field 是持有者 token, 值是重入次数
+var (
+	lockAcquireScript = redis.NewScript(`
+if redis.call('exists', KEYS[1]) == 0 or redis.call('hexists', KEYS[1], ARGV[1]) == 1 then
+	redis.call('hincrby', KEYS[1], ARGV[1], 1)
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return 1
+end
+return 0`)
+
+	lockReleaseScript = redis.NewScript(`
+if redis.call('hexists', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local n = redis.call('hincrby', KEYS[1], ARGV[1], -1)
+if n > 0 then
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return n
+end
+redis.call('del', KEYS[1])
+return 0`)
+
+	lockRenewScript = redis.NewScript(`
+if redis.call('hexists', KEYS[1], ARGV[1]) == 1 then
+	return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Lock 分布式锁
+// 同一个 owner 可以重入, 持有期间后台自动续期, 直到最后一次 Unlock
+type Lock struct {
+	client redis.UniversalClient
+	key    string
+	owner  string
+	ttl    time.Duration
+	retry  time.Duration
+
+	mu        sync.Mutex
+	holds     int
+	stopWatch chan struct{}
+}
+
+// NewLock 创建锁
+// name 是 Init 的配置名称, key 是锁的 redis key
+// 默认 ttl 30s, 每个 Lock 实例有唯一的 owner
+func NewLock(name string, key string) *Lock {
+	return &Lock{
+		client: Get(name),
+		key:    key,
+		owner:  newToken(),
+		ttl:    30 * time.Second,
+		retry:  100 * time.Millisecond,
+	}
+}
+
+// SetTTL 锁的过期时间, 持有期间每 ttl/3 续期一次, ttl 必须大于 0
+func (l *Lock) SetTTL(ttl time.Duration) *Lock {
+	if ttl <= 0 {
+		panic("wredis: lock ttl must be positive")
+	}
+	l.ttl = ttl
+	return l
+}
+
+// SetOwner 指定持有者, 相同 owner 的 Lock 可以重入
+func (l *Lock) SetOwner(owner string) *Lock {
+	l.owner = owner
+	return l
+}
+
+// SetRetryInterval 等待锁时的重试间隔
+func (l *Lock) SetRetryInterval(d time.Duration) *Lock {
+	l.retry = d
+	return l
+}
+
+func (l *Lock) Owner() string {
+	return l.owner
+}
+
+// TryLock 获取锁, 最多等待 wait, wait 为 0 只尝试一次
+// 超时返回 false, ctx 取消返回 ctx.Err()
+func (l *Lock) TryLock(ctx context.Context, wait time.Duration) (bool, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		ok, err := l.acquire(ctx)
+		if err != nil || ok {
+			return ok, err
+		}
+
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			return false, nil
+		}
+
+		timer := time.NewTimer(min(l.retry, remain))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Lock 阻塞直到获取锁或者 ctx 取消
+func (l *Lock) Lock(ctx context.Context) error {
+	for {
+		ok, err := l.acquire(ctx)
+		if err != nil || ok {
+			return err
+		}
+
+		timer := time.NewTimer(l.retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Unlock 释放一次锁, 重入次数为 0 时删除 key
+// 锁已经不属于当前 owner 返回 ErrLockNotHeld
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n, err := lockReleaseScript.Run(ctx, l.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if n < 0 {
+		l.holds = 0
+		l.stopWatchdog()
+		return ErrLockNotHeld
+	}
+
+	if l.holds > 0 {
+		l.holds--
+	}
+	if l.holds == 0 {
+		l.stopWatchdog()
+	}
+	return nil
+}
+
+func (l *Lock) acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ok, err := lockAcquireScript.Run(ctx, l.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Bool()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	l.holds++
+	if l.holds == 1 {
+		l.startWatchdog()
+	}
+	return true, nil
+}
+
+func (l *Lock) renew(ctx context.Context) (bool, error) {
+	return lockRenewScript.Run(ctx, l.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Bool()
+}
+
+// startWatchdog 后台续期 调用时必须持有 l.mu
+func (l *Lock) startWatchdog() {
+	stop := make(chan struct{})
+	l.stopWatch = stop
+
+	go func() {
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+				ok, err := l.renew(ctx)
+				cancel()
+				if err != nil {
+					logx.Errorf("redis lock %s 续期失败 %s", l.key, err.Error())
+					continue
+				}
+				if !ok {
+					logx.Errorf("redis lock %s 已经丢失 停止续期", l.key)
+					l.lost(stop)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// lost 锁已经丢失, 重置状态, 之后的 Lock 重新计数并启动续期
+func (l *Lock) lost(stop chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 已经 Unlock 或者换了新的续期协程
+	if l.stopWatch != stop {
+		return
+	}
+	l.holds = 0
+	l.stopWatch = nil
+}
+
+// stopWatchdog 停止续期 调用时必须持有 l.mu
+func (l *Lock) stopWatchdog() {
+	if l.stopWatch != nil {
+		close(l.stopWatch)
+		l.stopWatch = nil
+	}
+}
+
+// newToken 生成随机的唯一标识
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,124 @@
+package wredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockReentrant(t *testing.T) {
+	m := setupRedis(t, "lock")
+	ctx := context.Background()
+
+	l1 := NewLock("lock", "lock:job")
+	l2 := NewLock("lock", "lock:job")
+
+	ok, err := l1.TryLock(ctx, 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 同一个 owner 可以重入
+	ok, err = l1.TryLock(ctx, 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 其他 owner 拿不到
+	ok, err = l2.TryLock(ctx, 50*time.Millisecond)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// 其他 owner 不能释放
+	assert.ErrorIs(t, l2.Unlock(ctx), ErrLockNotHeld)
+
+	assert.Nil(t, l1.Unlock(ctx))
+	assert.True(t, m.Exists("lock:job"))
+	assert.Nil(t, l1.Unlock(ctx))
+	assert.False(t, m.Exists("lock:job"))
+
+	ok, err = l2.TryLock(ctx, 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, l2.Unlock(ctx))
+}
+
+func TestLockSameOwner(t *testing.T) {
+	setupRedis(t, "lock")
+	ctx := context.Background()
+
+	l1 := NewLock("lock", "lock:owner").SetOwner("order-1")
+	l2 := NewLock("lock", "lock:owner").SetOwner("order-1")
+
+	assert.Nil(t, l1.Lock(ctx))
+	assert.Nil(t, l2.Lock(ctx))
+	assert.Nil(t, l2.Unlock(ctx))
+	assert.Nil(t, l1.Unlock(ctx))
+}
+
+func TestLockCancel(t *testing.T) {
+	setupRedis(t, "lock")
+
+	l1 := NewLock("lock", "lock:cancel")
+	l2 := NewLock("lock", "lock:cancel")
+
+	assert.Nil(t, l1.Lock(context.Background()))
+	defer l1.Unlock(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l2.Lock(ctx), context.DeadlineExceeded)
+
+	ok, err := l2.TryLock(ctx, time.Second)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLockRenew(t *testing.T) {
+	m := setupRedis(t, "lock")
+	ctx := context.Background()
+
+	l := NewLock("lock", "lock:renew").SetTTL(300 * time.Millisecond)
+	assert.Nil(t, l.Lock(ctx))
+
+	m.FastForward(200 * time.Millisecond)
+	ok, err := l.renew(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 300*time.Millisecond, m.TTL("lock:renew"))
+
+	assert.Nil(t, l.Unlock(ctx))
+	ok, err = l.renew(ctx)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestLockLostReacquire(t *testing.T) {
+	m := setupRedis(t, "lock")
+	ctx := context.Background()
+
+	l := NewLock("lock", "lock:lost").SetTTL(150 * time.Millisecond)
+	assert.Nil(t, l.Lock(ctx))
+
+	// 锁被删除, 续期失败后重置状态
+	m.Del("lock:lost")
+	assert.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.holds == 0 && l.stopWatch == nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, l.Lock(ctx))
+	l.mu.Lock()
+	assert.Equal(t, 1, l.holds)
+	assert.NotNil(t, l.stopWatch)
+	l.mu.Unlock()
+
+	assert.Nil(t, l.Unlock(ctx))
+	assert.False(t, m.Exists("lock:lost"))
+}
+
+func TestLockInvalidTTL(t *testing.T) {
+	setupRedis(t, "lock")
+	assert.Panics(t, func() { NewLock("lock", "lock:ttl").SetTTL(0) })
+}
@@ -0,0 +1,181 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+)
+
+const metricNamespace = "wredis"
+
+var (
+	metricCmdDuration = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: metricNamespace,
+		Subsystem: "requests",
+		Name:      "duration_ms",
+		Help:      "wredis requests duration(ms).",
+		Labels:    []string{"name", "command"},
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000},
+	})
+	metricCmdError = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: metricNamespace,
+		Subsystem: "requests",
+		Name:      "error_total",
+		Help:      "wredis requests error count.",
+		Labels:    []string{"name", "command"},
+	})
+)
+
+// 检查写入值大小的命令 和 值所在的参数位置
+var bigValueArgCmds = map[string]int{
+	"set": 2, "setnx": 2, "getset": 2, "append": 2, "setex": 3, "psetex": 3,
+}
+
+// 检查返回值大小的命令
+var bigValueResultCmds = map[string]bool{
+	"get": true, "getset": true, "getdel": true, "getex": true,
+}
+
+// metricsHook 记录命令耗时和错误数, 慢命令和大 value 打日志
+type metricsHook struct {
+	name          string
+	metrics       bool
+	slowThreshold time.Duration
+	bigValueSize  int
+}
+
+func newMetricsHook(name string, cfg Cfg) *metricsHook {
+	return &metricsHook{
+		name:          name,
+		metrics:       cfg.Metrics,
+		slowThreshold: cfg.SlowThreshold,
+		bigValueSize:  cfg.BigValueSize,
+	}
+}
+
+func (h *metricsHook) enabled() bool {
+	return h.metrics || h.slowThreshold > 0 || h.bigValueSize > 0
+}
+
+func (h *metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		duration := time.Since(start)
+
+		// 单个命令执行完 hook 返回后才设置 cmd.Err, 使用返回的错误
+		h.record(cmd, err, duration)
+		if h.slowThreshold > 0 && duration > h.slowThreshold {
+			logx.WithContext(ctx).WithDuration(duration).Slowf("[REDIS] %s 慢命令 %s", h.name, cmdString(cmd))
+		}
+		h.checkBigValue(ctx, cmd)
+		return err
+	}
+}
+
+func (h *metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		duration := time.Since(start)
+
+		for _, cmd := range cmds {
+			h.record(cmd, cmd.Err(), duration)
+			h.checkBigValue(ctx, cmd)
+		}
+		if h.slowThreshold > 0 && duration > h.slowThreshold {
+			names := make([]string, len(cmds))
+			for i, cmd := range cmds {
+				names[i] = cmdString(cmd)
+			}
+			logx.WithContext(ctx).WithDuration(duration).Slowf("[REDIS] %s 慢 pipeline %d 个命令 %s",
+				h.name, len(cmds), strings.Join(names, ", "))
+		}
+		return err
+	}
+}
+
+func (h *metricsHook) record(cmd redis.Cmder, err error, duration time.Duration) {
+	if !h.metrics {
+		return
+	}
+	name := strings.ToLower(cmd.Name())
+	metricCmdDuration.ObserveFloat(float64(duration)/float64(time.Millisecond), h.name, name)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		metricCmdError.Inc(h.name, name)
+	}
+}
+
+func (h *metricsHook) checkBigValue(ctx context.Context, cmd redis.Cmder) {
+	if h.bigValueSize <= 0 {
+		return
+	}
+
+	name := strings.ToLower(cmd.Name())
+	args := cmd.Args()
+	if pos, ok := bigValueArgCmds[name]; ok && pos < len(args) {
+		if size := valueSize(args[pos]); size > h.bigValueSize {
+			logx.WithContext(ctx).Slowf("[REDIS] %s 大 value 写入 %s %v %d 字节", h.name, name, args[1], size)
+		}
+	}
+	if bigValueResultCmds[name] {
+		if c, ok := cmd.(*redis.StringCmd); ok && len(c.Val()) > h.bigValueSize {
+			logx.WithContext(ctx).Slowf("[REDIS] %s 大 value 读取 %s %v %d 字节", h.name, name, args[1], len(c.Val()))
+		}
+	}
+}
+
+// PoolStats 所有客户端的连接池状态, key 是 Init 的配置名称
+func PoolStats() map[string]*redis.PoolStats {
+	stats := make(map[string]*redis.PoolStats)
+	instance.Range(func(key, value any) bool {
+		name, _ := key.(string)
+		stats[name] = value.(redis.UniversalClient).PoolStats()
+		return true
+	})
+	return stats
+}
+
+func valueSize(v any) int {
+	switch s := v.(type) {
+	case string:
+		return len(s)
+	case []byte:
+		return len(s)
+	}
+	return 0
+}
+
+// cmdString 命令名称和 key, 不包含值, 参数里可能有密码 token 等敏感数据
+// key 太长时截断
+func cmdString(cmd redis.Cmder) string {
+	args := cmd.Args()
+	var b strings.Builder
+	b.WriteString(strings.ToLower(cmd.Name()))
+	for _, i := range keyIndexes(cmd.Name(), args) {
+		s := fmt.Sprint(args[i])
+		if len(s) > 64 {
+			s = s[:64] + "..."
+		}
+		b.WriteByte(' ')
+		b.WriteString(s)
+		if b.Len() > 256 {
+			b.WriteString(" ...")
+			break
+		}
+	}
+	return b.String()
+}
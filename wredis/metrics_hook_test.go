@@ -0,0 +1,97 @@
+package wredis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+	zprom "github.com/zeromicro/go-zero/core/prometheus"
+)
+
+// findMetric 从默认的 registry 读取指定标签的指标
+func findMetric(t *testing.T, name, client, command string) *dto.Metric {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.Nil(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["name"] == client && labels["command"] == command {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+func TestMetricsHook(t *testing.T) {
+	zprom.Enable()
+	logs := logtest.NewCollector(t)
+	setupRedisCfg(t, "metrics", Cfg{
+		Metrics:       true,
+		SlowThreshold: time.Nanosecond,
+		BigValueSize:  8,
+	})
+	ctx := context.Background()
+	client := Get("metrics")
+
+	secret := strings.Repeat("x", 100)
+	assert.Nil(t, client.Set(ctx, "big", secret, 0).Err())
+	assert.Equal(t, 100, len(client.Get(ctx, "big").Val()))
+	assert.NotNil(t, client.Get(ctx, "missing").Err())
+	assert.NotNil(t, client.IncrBy(ctx, "big", 1).Err())
+
+	// 耗时直方图
+	m := findMetric(t, "wredis_requests_duration_ms", "metrics", "get")
+	assert.NotNil(t, m)
+	assert.Equal(t, uint64(2), m.GetHistogram().GetSampleCount())
+
+	// redis.Nil 不算错误
+	assert.Nil(t, findMetric(t, "wredis_requests_error_total", "metrics", "get"))
+	m = findMetric(t, "wredis_requests_error_total", "metrics", "incrby")
+	assert.NotNil(t, m)
+	assert.Equal(t, float64(1), m.GetCounter().GetValue())
+
+	// 大 value 和慢命令是 slow 级别, 只记录命令和 key, 不记录值
+	content := logs.String()
+	assert.Contains(t, content, "大 value 写入 set big 100 字节")
+	assert.Contains(t, content, "大 value 读取 get big 100 字节")
+	assert.Contains(t, content, "慢命令 set big")
+	assert.Contains(t, content, `"level":"slow"`)
+	assert.NotContains(t, content, `"level":"info"`)
+	assert.NotContains(t, content, secret[:16])
+
+	stats := PoolStats()
+	assert.Contains(t, stats, "metrics")
+	assert.True(t, stats["metrics"].TotalConns > 0)
+}
+
+func TestCmdString(t *testing.T) {
+	ctx := context.Background()
+
+	cmd := redis.NewStatusCmd(ctx, "set", "k", "password", "ex", 10)
+	assert.Equal(t, "set k", cmdString(cmd))
+
+	cmd = redis.NewStatusCmd(ctx, "auth", "user", "password")
+	assert.Equal(t, "auth", cmdString(cmd))
+
+	cmd = redis.NewStatusCmd(ctx, "mset", "a", "1", "b", "2")
+	assert.Equal(t, "mset a b", cmdString(cmd))
+
+	cmd = redis.NewStatusCmd(ctx, "evalsha", "sha", 2, "k1", "k2", "token")
+	assert.Equal(t, "evalsha k1 k2", cmdString(cmd))
+
+	cmd = redis.NewStatusCmd(ctx, "get", strings.Repeat("k", 100))
+	assert.True(t, strings.HasSuffix(cmdString(cmd), "..."))
+}
@@ -0,0 +1,355 @@
+package wredis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Key 拼接 redis key, 例如 Key("user", 1, "profile") 返回 user:1:profile
+func Key(parts ...any) string {
+	s := make([]string, len(parts))
+	for i, p := range parts {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, ":")
+}
+
+// 命令参数中 key 的位置
+type keySpec int
+
+const (
+	keyFirst       keySpec = iota // 第一个参数
+	keyNone                       // 没有 key
+	keySecond                     // 第二个参数 OBJECT ENCODING key
+	keyFirstTwo                   // 前两个参数 RENAME src dst
+	keyAll                        // 所有参数 DEL k1 k2
+	keyAllButLast                 // 除了最后一个参数 BLPOP k1 k2 timeout
+	keyPairs                      // 奇数位置 MSET k1 v1 k2 v2
+	keyNumKeys1                   // 第一个参数是 key 数量 ZUNION numkeys k1 k2
+	keyNumKeys2                   // 第二个参数是 key 数量 EVAL script numkeys k1 k2
+	keyDestNumKeys                // 第一个是 key 第二个是 key 数量 ZUNIONSTORE dest numkeys k1 k2
+	keyStreams                    // STREAMS 后面的一半参数 XREAD STREAMS k1 k2 id1 id2
+	keyScan                       // SCAN 的 MATCH 参数
+	keyFromSecond                 // 第二个参数开始 BITOP AND dest k1 k2
+	keyFirstStore                 // 第一个参数和 STORE STOREDIST 后面的参数 SORT key STORE dest
+)
+
+var keySpecs = map[string]keySpec{
+	"del": keyAll, "exists": keyAll, "unlink": keyAll, "touch": keyAll, "watch": keyAll, "mget": keyAll,
+	"sinter": keyAll, "sunion": keyAll, "sdiff": keyAll, "sinterstore": keyAll, "sunionstore": keyAll,
+	"sdiffstore": keyAll, "pfcount": keyAll, "pfmerge": keyAll, "rename": keyAll, "renamenx": keyAll,
+	"rpoplpush": keyAll,
+
+	"smove": keyFirstTwo, "lmove": keyFirstTwo, "blmove": keyFirstTwo, "brpoplpush": keyFirstTwo,
+	"copy": keyFirstTwo, "lcs": keyFirstTwo, "zrangestore": keyFirstTwo, "geosearchstore": keyFirstTwo,
+
+	"blpop": keyAllButLast, "brpop": keyAllButLast, "bzpopmin": keyAllButLast, "bzpopmax": keyAllButLast,
+
+	"mset": keyPairs, "msetnx": keyPairs,
+
+	"zunion": keyNumKeys1, "zinter": keyNumKeys1, "zdiff": keyNumKeys1, "zintercard": keyNumKeys1,
+	"sintercard": keyNumKeys1, "lmpop": keyNumKeys1, "zmpop": keyNumKeys1,
+
+	"eval": keyNumKeys2, "evalsha": keyNumKeys2, "eval_ro": keyNumKeys2, "evalsha_ro": keyNumKeys2,
+	"fcall": keyNumKeys2, "fcall_ro": keyNumKeys2, "blmpop": keyNumKeys2, "bzmpop": keyNumKeys2,
+
+	"zunionstore": keyDestNumKeys, "zinterstore": keyDestNumKeys, "zdiffstore": keyDestNumKeys,
+
+	"xread": keyStreams, "xreadgroup": keyStreams,
+
+	"scan": keyScan,
+
+	"object": keySecond, "memory": keySecond, "xinfo": keySecond, "xgroup": keySecond,
+
+	"bitop": keyFromSecond,
+
+	"sort": keyFirstStore, "georadius": keyFirstStore, "georadiusbymember": keyFirstStore,
+
+	"ping": keyNone, "echo": keyNone, "info": keyNone, "config": keyNone, "client": keyNone,
+	"select": keyNone, "auth": keyNone, "hello": keyNone, "quit": keyNone, "time": keyNone,
+	"dbsize": keyNone, "flushdb": keyNone, "flushall": keyNone, "multi": keyNone, "exec": keyNone,
+	"discard": keyNone, "unwatch": keyNone, "script": keyNone, "function": keyNone, "command": keyNone,
+	"cluster": keyNone, "readonly": keyNone, "readwrite": keyNone, "publish": keyNone, "spublish": keyNone,
+	"subscribe": keyNone, "psubscribe": keyNone, "ssubscribe": keyNone, "unsubscribe": keyNone,
+	"punsubscribe": keyNone, "sunsubscribe": keyNone, "pubsub": keyNone, "save": keyNone,
+	"bgsave": keyNone, "bgrewriteaof": keyNone, "lastsave": keyNone, "slowlog": keyNone, "debug": keyNone,
+	"monitor": keyNone, "role": keyNone, "wait": keyNone, "randomkey": keyNone, "swapdb": keyNone,
+	"latency": keyNone, "module": keyNone, "acl": keyNone, "shutdown": keyNone, "lolwut": keyNone,
+}
+
+// prefixHook 给所有命令的 key 加上前缀, 返回 key 的命令去掉前缀
+type prefixHook struct {
+	prefix string
+}
+
+func newPrefixHook(prefix string) *prefixHook {
+	return &prefixHook{prefix: prefix}
+}
+
+func (h *prefixHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *prefixHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		run := h.addPrefix(cmd)
+		err := next(ctx, run)
+		copyResult(cmd, run)
+		h.stripPrefix(cmd)
+		return err
+	}
+}
+
+func (h *prefixHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		runs := make([]redis.Cmder, len(cmds))
+		for i, cmd := range cmds {
+			runs[i] = h.addPrefix(cmd)
+		}
+		err := next(ctx, runs)
+		for i, cmd := range cmds {
+			copyResult(cmd, runs[i])
+			h.stripPrefix(cmd)
+		}
+		return err
+	}
+}
+
+// addPrefix 返回加上前缀后实际执行的命令, 原命令的参数不变
+// SCAN 系列的迭代器会用同一个命令重复执行, 修改原命令的参数会重复添加前缀
+func (h *prefixHook) addPrefix(cmd redis.Cmder) redis.Cmder {
+	args := cmd.Args()
+	idx := keyIndexes(cmd.Name(), args)
+	// SCAN 没有 MATCH 时加上 MATCH prefix*, 只扫描当前命名空间的 key
+	scan := keySpecs[strings.ToLower(cmd.Name())] == keyScan && len(args) >= 2 && !hasMatch(args)
+	if len(idx) == 0 && !scan {
+		return cmd
+	}
+
+	runArgs := make([]any, len(args), len(args)+2)
+	copy(runArgs, args)
+	for _, i := range idx {
+		switch v := runArgs[i].(type) {
+		case string:
+			runArgs[i] = h.prefix + v
+		case []byte:
+			runArgs[i] = h.prefix + string(v)
+		}
+	}
+	if scan {
+		runArgs = append(runArgs[:2], append([]any{"match", globEscape(h.prefix) + "*"}, runArgs[2:]...)...)
+	}
+	return cloneCmd(cmd, runArgs)
+}
+
+// cloneCmd 复制命令, 使用新的参数
+// go-redis 没有导出复制命令的方法, 所有命令都嵌入了 baseCmd, 通过反射替换 args
+func cloneCmd(cmd redis.Cmder, args []any) redis.Cmder {
+	v := reflect.ValueOf(cmd).Elem()
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+	setArgs(cp.Elem(), args)
+	return cp.Interface().(redis.Cmder)
+}
+
+// copyResult 把执行的命令的结果写回原命令, 原命令的参数不变
+func copyResult(cmd, run redis.Cmder) {
+	if cmd == run {
+		return
+	}
+	args := cmd.Args()
+	v := reflect.ValueOf(cmd).Elem()
+	v.Set(reflect.ValueOf(run).Elem())
+	setArgs(v, args)
+}
+
+func setArgs(v reflect.Value, args []any) {
+	f := v.FieldByName("args")
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(reflect.ValueOf(args))
+}
+
+// keyIndexes 命令参数中 key 的位置, SCAN 返回 MATCH 参数的位置
+func keyIndexes(name string, args []any) []int {
+	if len(args) < 2 {
+		return nil
+	}
+	spec, ok := keySpecs[strings.ToLower(name)]
+	if !ok {
+		spec = keyFirst
+	}
+
+	var idx []int
+	add := func(i int) {
+		if i > 0 && i < len(args) {
+			idx = append(idx, i)
+		}
+	}
+	switch spec {
+	case keyFirst:
+		add(1)
+	case keySecond:
+		add(2)
+	case keyFirstTwo:
+		add(1)
+		add(2)
+	case keyFromSecond:
+		for i := 2; i < len(args); i++ {
+			add(i)
+		}
+	case keyFirstStore:
+		add(1)
+		for i := 2; i < len(args)-1; i++ {
+			if s, ok := args[i].(string); ok && (strings.EqualFold(s, "store") || strings.EqualFold(s, "storedist")) {
+				add(i + 1)
+				i++
+			}
+		}
+	case keyAll:
+		for i := 1; i < len(args); i++ {
+			add(i)
+		}
+	case keyAllButLast:
+		for i := 1; i < len(args)-1; i++ {
+			add(i)
+		}
+	case keyPairs:
+		for i := 1; i < len(args); i += 2 {
+			add(i)
+		}
+	case keyNumKeys1:
+		for i := 0; i < toInt(args[1]); i++ {
+			add(2 + i)
+		}
+	case keyNumKeys2:
+		if len(args) > 2 {
+			for i := 0; i < toInt(args[2]); i++ {
+				add(3 + i)
+			}
+		}
+	case keyDestNumKeys:
+		add(1)
+		if len(args) > 2 {
+			for i := 0; i < toInt(args[2]); i++ {
+				add(3 + i)
+			}
+		}
+	case keyStreams:
+		for i, arg := range args {
+			if s, ok := arg.(string); ok && strings.EqualFold(s, "streams") {
+				n := (len(args) - i - 1) / 2
+				for j := 0; j < n; j++ {
+					add(i + 1 + j)
+				}
+				break
+			}
+		}
+	case keyScan:
+		for i := 2; i < len(args)-1; i++ {
+			if s, ok := args[i].(string); ok && strings.EqualFold(s, "match") {
+				add(i + 1)
+				break
+			}
+		}
+	}
+	return idx
+}
+
+func hasMatch(args []any) bool {
+	for i := 2; i < len(args)-1; i++ {
+		if s, ok := args[i].(string); ok && strings.EqualFold(s, "match") {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *prefixHook) stripPrefix(cmd redis.Cmder) {
+	if cmd.Err() != nil {
+		return
+	}
+
+	switch c := cmd.(type) {
+	case *redis.ScanCmd:
+		if strings.ToLower(cmd.Name()) != "scan" {
+			return
+		}
+		// MATCH 不是前缀开头时可能扫描到其他前缀的 key, 需要过滤掉
+		page, cursor := c.Val()
+		c.SetVal(h.stripKeys(page), cursor)
+	case *redis.StringSliceCmd:
+		switch strings.ToLower(cmd.Name()) {
+		case "keys":
+			c.SetVal(h.stripKeys(c.Val()))
+		case "blpop", "brpop":
+			val := c.Val()
+			if len(val) > 0 {
+				val[0] = strings.TrimPrefix(val[0], h.prefix)
+			}
+		}
+	case *redis.StringCmd:
+		if strings.ToLower(cmd.Name()) == "randomkey" {
+			c.SetVal(strings.TrimPrefix(c.Val(), h.prefix))
+		}
+	case *redis.ZWithKeyCmd:
+		if val := c.Val(); val != nil {
+			val.Key = strings.TrimPrefix(val.Key, h.prefix)
+		}
+	case *redis.ZSliceWithKeyCmd:
+		key, val := c.Val()
+		c.SetVal(strings.TrimPrefix(key, h.prefix), val)
+	case *redis.KeyValuesCmd:
+		key, val := c.Val()
+		c.SetVal(strings.TrimPrefix(key, h.prefix), val)
+	case *redis.XStreamSliceCmd:
+		val := c.Val()
+		for i := range val {
+			val[i].Stream = strings.TrimPrefix(val[i].Stream, h.prefix)
+		}
+	}
+}
+
+// stripKeys 去掉前缀, 没有前缀的 key 不属于当前命名空间, 丢弃
+func (h *prefixHook) stripKeys(keys []string) []string {
+	result := keys[:0]
+	for _, k := range keys {
+		if strings.HasPrefix(k, h.prefix) {
+			result = append(result, strings.TrimPrefix(k, h.prefix))
+		}
+	}
+	return result
+}
+
+// globEscape 转义 MATCH 模式的特殊字符
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package wredis
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "user:1:profile", Key("user", 1, "profile"))
+	assert.Equal(t, "user", Key("user"))
+}
+
+func TestPrefixHook(t *testing.T) {
+	m := setupRedisCfg(t, "prefix", Cfg{KeyPrefix: "app:"})
+	ctx := context.Background()
+	client := Get("prefix")
+
+	// 其他服务的 key
+	assert.Nil(t, m.Set("other:x", "1"))
+
+	assert.Nil(t, client.Set(ctx, "a", "1", time.Minute).Err())
+	assert.Equal(t, "1", client.Get(ctx, "a").Val())
+	v, _ := m.Get("app:a")
+	assert.Equal(t, "1", v)
+
+	assert.Nil(t, client.MSet(ctx, "b", "2", "c", "3").Err())
+	assert.Equal(t, []any{"1", "2", "3"}, client.MGet(ctx, "a", "b", "c").Val())
+
+	// pipeline
+	cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, "n")
+		pipe.Incr(ctx, "n")
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), cmds[1].(*redis.IntCmd).Val())
+	assert.True(t, m.Exists("app:n"))
+
+	// lua KEYS
+	script := redis.NewScript(`return redis.call('get', KEYS[1])`)
+	assert.Equal(t, "2", script.Run(ctx, client, []string{"b"}).Val())
+
+	// 返回的 key 去掉前缀, 不返回其他前缀的 key
+	keys := client.Keys(ctx, "*").Val()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b", "c", "n"}, keys)
+
+	var scanned []string
+	iter := client.Scan(ctx, 0, "", 2).Iterator()
+	for iter.Next(ctx) {
+		scanned = append(scanned, iter.Val())
+	}
+	assert.Nil(t, iter.Err())
+	sort.Strings(scanned)
+	assert.Equal(t, []string{"a", "b", "c", "n"}, scanned)
+
+	page, _ := client.Scan(ctx, 0, "a*", 100).Val()
+	assert.Equal(t, []string{"a"}, page)
+
+	assert.Nil(t, client.RPush(ctx, "q", "job").Err())
+	assert.Equal(t, []string{"q", "job"}, client.BLPop(ctx, time.Second, "q").Val())
+
+	assert.Equal(t, int64(3), client.Del(ctx, "a", "b", "c").Val())
+	assert.False(t, m.Exists("app:a"))
+	assert.True(t, m.Exists("other:x"))
+}
+
+func TestPrefixHookSubcommands(t *testing.T) {
+	m := setupRedisCfg(t, "prefix", Cfg{KeyPrefix: "app:"})
+	ctx := context.Background()
+	client := Get("prefix")
+
+	// XGROUP CREATE key group id, key 是第二个参数
+	assert.Nil(t, client.XGroupCreateMkStream(ctx, "s", "g", "0").Err())
+	assert.True(t, m.Exists("app:s"))
+	assert.False(t, m.Exists("app:create"))
+	assert.Nil(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "s", Values: map[string]any{"k": "v"}}).Err())
+	streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "c", Streams: []string{"s", ">"}, Count: 1}).Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "s", streams[0].Stream)
+
+	// BITOP op dest key...
+	assert.Nil(t, client.SetBit(ctx, "b1", 1, 1).Err())
+	assert.Nil(t, client.SetBit(ctx, "b2", 1, 1).Err())
+	assert.Nil(t, client.BitOpAnd(ctx, "b3", "b1", "b2").Err())
+	assert.True(t, m.Exists("app:b3"))
+	assert.Equal(t, int64(1), client.GetBit(ctx, "b3", 1).Val())
+
+	// SCAN 没有 MATCH 时只扫描当前前缀
+	assert.Nil(t, m.Set("other:x", "1"))
+	page, _, err := client.Scan(ctx, 0, "", 100).Result()
+	assert.Nil(t, err)
+	sort.Strings(page)
+	assert.Equal(t, []string{"b1", "b2", "b3", "s"}, page)
+}
+
+func TestPrefixHookStoreArgs(t *testing.T) {
+	hook := newPrefixHook("app:")
+	ctx := context.Background()
+
+	// 执行的是复制的命令, 原命令参数不变
+	sortCmd := redis.NewIntCmd(ctx, "sort", "l", "limit", 0, 10, "store", "dst")
+	run := hook.addPrefix(sortCmd)
+	assert.Equal(t, []any{"sort", "app:l", "limit", 0, 10, "store", "app:dst"}, run.Args())
+	assert.Equal(t, []any{"sort", "l", "limit", 0, 10, "store", "dst"}, sortCmd.Args())
+
+	geoCmd := redis.NewIntCmd(ctx, "georadius", "g", 1.0, 2.0, 10, "km", "STORE", "d1", "STOREDIST", "d2")
+	run = hook.addPrefix(geoCmd)
+	assert.Equal(t, []any{"georadius", "app:g", 1.0, 2.0, 10, "km", "STORE", "app:d1", "STOREDIST", "app:d2"}, run.Args())
+
+	lcsCmd := redis.NewStringCmd(ctx, "lcs", "k1", "k2")
+	run = hook.addPrefix(lcsCmd)
+	assert.Equal(t, []any{"lcs", "app:k1", "app:k2"}, run.Args())
+
+	// 替换后的命令带 MATCH
+	scanCmd := redis.NewScanCmd(ctx, nil, "scan", 0, "count", 10)
+	run = hook.addPrefix(scanCmd)
+	assert.Equal(t, []any{"scan", 0, "match", "app:*", "count", 10}, run.Args())
+	assert.Equal(t, []any{"scan", 0, "count", 10}, scanCmd.Args())
+
+	// 结果写回原命令
+	run.(*redis.ScanCmd).SetVal([]string{"app:a"}, 7)
+	copyResult(scanCmd, run)
+	hook.stripPrefix(scanCmd)
+	page, cursor := scanCmd.Val()
+	assert.Equal(t, []string{"a"}, page)
+	assert.Equal(t, uint64(7), cursor)
+	assert.Equal(t, []any{"scan", 0, "count", 10}, scanCmd.Args())
+
+	zmpop := redis.NewZSliceWithKeyCmd(ctx, "zmpop", 1, "z", "min")
+	zmpop.SetVal("app:z", []redis.Z{{Score: 1, Member: "m"}})
+	hook.stripPrefix(zmpop)
+	key, _ := zmpop.Val()
+	assert.Equal(t, "z", key)
+
+	assert.Equal(t, `a\*b\?:`, globEscape("a*b?:"))
+}
+
+func TestPrefixHookKeyWithPrefix(t *testing.T) {
+	m := setupRedisCfg(t, "prefix", Cfg{KeyPrefix: "app:"})
+	ctx := context.Background()
+	client := Get("prefix")
+
+	// key 本身以前缀开头时也要加前缀
+	assert.Nil(t, client.HSet(ctx, "app:h", "f1", "1", "f2", "2").Err())
+	assert.True(t, m.Exists("app:app:h"))
+
+	var fields []string
+	iter := client.HScan(ctx, "app:h", 0, "", 1).Iterator()
+	for iter.Next(ctx) {
+		fields = append(fields, iter.Val())
+	}
+	assert.Nil(t, iter.Err())
+	sort.Strings(fields)
+	assert.Equal(t, []string{"1", "2", "f1", "f2"}, fields)
+
+	// 同一个命令执行两次只加一次前缀
+	cmd := redis.NewStringCmd(ctx, "get", "app:h2")
+	assert.Nil(t, client.Set(ctx, "app:h2", "v", 0).Err())
+	assert.Nil(t, client.Process(ctx, cmd))
+	assert.Nil(t, client.Process(ctx, cmd))
+	assert.Equal(t, "v", cmd.Val())
+	assert.Equal(t, []any{"get", "app:h2"}, cmd.Args())
+}
@@ -0,0 +1,291 @@
+package wredis
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionReservedKey = errors.New("session key starting with _ is reserved")
+)
+
+// sessionSetScript 会话存在时才写入, 已销毁的会话不会被正在处理的请求重新创建
+var sessionSetScript = redis.NewScript(`
+if redis.call('exists', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('hset', KEYS[1], ARGV[1], ARGV[2])
+redis.call('pexpire', KEYS[1], ARGV[3])
+return 1
+`)
+
+// 保留字段 以 _ 开头
+const (
+	sessionUserField    = "_uid"
+	sessionCreatedField = "_ct"
+)
+
+// Session 会话数据, 存为 redis hash, 每个字段是 json 编码的值
+// 不是并发安全的, 只在一个请求内使用
+type Session struct {
+	store  *SessionStore
+	id     string
+	values map[string]string
+}
+
+func (s *Session) Id() string {
+	return s.id
+}
+
+// UserId 登录的用户, 未登录为空
+func (s *Session) UserId() string {
+	return s.values[sessionUserField]
+}
+
+// Get 读取字段解码到 v, 不存在时返回 false
+func (s *Session) Get(key string, v any) (bool, error) {
+	data, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal([]byte(data), v)
+}
+
+// Set 写入字段, 以 _ 开头的字段名是保留的
+// 会话已经销毁时返回 ErrSessionNotFound
+func (s *Session) Set(ctx context.Context, key string, v any) error {
+	if strings.HasPrefix(key, "_") {
+		return ErrSessionReservedKey
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ok, err := sessionSetScript.Run(ctx, s.store.client, []string{s.store.key(s.id)},
+		key, data, s.store.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrSessionNotFound
+	}
+	s.values[key] = string(data)
+	return nil
+}
+
+// Delete 删除字段
+func (s *Session) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := s.store.client.HDel(ctx, s.store.key(s.id), keys...).Err(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(s.values, key)
+	}
+	return nil
+}
+
+// SessionStore 基于 redis 的会话存储
+// 会话 id 签名后放在 cookie 里, 每次读取时延长过期时间
+type SessionStore struct {
+	client     redis.UniversalClient
+	prefix     string
+	secret     []byte
+	ttl        time.Duration
+	cookieName string
+	domain     string
+	secure     bool
+}
+
+// NewSessionStore name 是 Init 的配置名称, prefix 是 redis key 前缀, secret 是 cookie 签名密钥
+// 默认 30 分钟不访问过期, cookie 名称是 sid
+func NewSessionStore(name, prefix string, secret []byte) *SessionStore {
+	return &SessionStore{
+		client:     Get(name),
+		prefix:     prefix,
+		secret:     secret,
+		ttl:        30 * time.Minute,
+		cookieName: "sid",
+	}
+}
+
+// SetTTL 多久不访问过期
+func (s *SessionStore) SetTTL(ttl time.Duration) *SessionStore {
+	s.ttl = ttl
+	return s
+}
+
+func (s *SessionStore) SetCookieName(name string) *SessionStore {
+	s.cookieName = name
+	return s
+}
+
+func (s *SessionStore) SetCookieDomain(domain string) *SessionStore {
+	s.domain = domain
+	return s
+}
+
+// SetSecure cookie 只在 https 下发送
+func (s *SessionStore) SetSecure(secure bool) *SessionStore {
+	s.secure = secure
+	return s
+}
+
+// Create 创建新会话并写 cookie
+func (s *SessionStore) Create(ctx context.Context, w http.ResponseWriter) (*Session, error) {
+	return s.create(ctx, w, map[string]string{})
+}
+
+// Load 读取会话并延长过期时间, 不存在返回 ErrSessionNotFound
+func (s *SessionStore) Load(ctx context.Context, id string) (*Session, error) {
+	values, err := s.client.HGetAll(ctx, s.key(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.PExpire(ctx, s.key(id), s.ttl)
+		if uid := values[sessionUserField]; uid != "" {
+			pipe.PExpire(ctx, s.userKey(uid), s.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Session{store: s, id: id, values: values}, nil
+}
+
+// LoadRequest 从请求的 cookie 读取会话, cookie 不存在或签名错误返回 ErrSessionNotFound
+func (s *SessionStore) LoadRequest(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(s.cookieName)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	id, ok := s.verify(cookie.Value)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return s.Load(r.Context(), id)
+}
+
+// Login 登录, 更换会话 id 防止会话固定攻击, 原会话的数据保留
+// old 为 nil 时创建新会话
+func (s *SessionStore) Login(ctx context.Context, w http.ResponseWriter, old *Session, userId string) (*Session, error) {
+	values := map[string]string{}
+	if old != nil {
+		for k, v := range old.values {
+			values[k] = v
+		}
+		if err := s.Destroy(ctx, w, old); err != nil {
+			return nil, err
+		}
+	}
+	values[sessionUserField] = userId
+	return s.create(ctx, w, values)
+}
+
+// Destroy 删除会话并清除 cookie
+func (s *SessionStore) Destroy(ctx context.Context, w http.ResponseWriter, session *Session) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.key(session.id))
+		if uid := session.UserId(); uid != "" {
+			pipe.SRem(ctx, s.userKey(uid), session.id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, s.cookie("", -1))
+	return nil
+}
+
+// DestroyUser 删除用户的所有会话, 所有设备退出登录
+func (s *SessionStore) DestroyUser(ctx context.Context, userId string) error {
+	ids, err := s.client.SMembers(ctx, s.userKey(userId)).Result()
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			pipe.Del(ctx, s.key(id))
+		}
+		pipe.Del(ctx, s.userKey(userId))
+		return nil
+	})
+	return err
+}
+
+func (s *SessionStore) create(ctx context.Context, w http.ResponseWriter, values map[string]string) (*Session, error) {
+	id := newToken()
+	values[sessionCreatedField] = strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.key(id), values)
+		pipe.PExpire(ctx, s.key(id), s.ttl)
+		if uid := values[sessionUserField]; uid != "" {
+			pipe.SAdd(ctx, s.userKey(uid), id)
+			pipe.PExpire(ctx, s.userKey(uid), s.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, s.cookie(s.sign(id), 0))
+	return &Session{store: s, id: id, values: values}, nil
+}
+
+func (s *SessionStore) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.cookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   s.domain,
+		MaxAge:   maxAge,
+		Secure:   s.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// sign cookie 值 id.签名
+func (s *SessionStore) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *SessionStore) verify(value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(value), []byte(s.sign(id)))
+}
+
+func (s *SessionStore) key(id string) string {
+	return s.prefix + ":" + id
+}
+
+func (s *SessionStore) userKey(userId string) string {
+	return s.prefix + ":user:" + userId
+}
@@ -0,0 +1,41 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+type sessionCtxKey struct{}
+
+// SessionMiddleware go-zero 中间件, 读取 cookie 中的会话放到 context
+// 没有会话时不创建, handler 需要时调用 store.Create 或 store.Login
+func SessionMiddleware(store *SessionStore) rest.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			session, err := store.LoadRequest(r)
+			if err != nil {
+				if !errors.Is(err, ErrSessionNotFound) {
+					logx.WithContext(r.Context()).Errorf("读取会话失败 %s", err.Error())
+				}
+				next(w, r)
+				return
+			}
+			next(w, r.WithContext(WithSession(r.Context(), session)))
+		}
+	}
+}
+
+// WithSession 把会话放到 context
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionCtxKey{}, session)
+}
+
+// SessionFromContext 获取 SessionMiddleware 放到 context 的会话
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionCtxKey{}).(*Session)
+	return session, ok
+}
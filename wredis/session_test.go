@@ -0,0 +1,89 @@
+package wredis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStore(t *testing.T) {
+	m := setupRedis(t, "session")
+	ctx := context.Background()
+
+	store := NewSessionStore("session", "sess", []byte("secret")).SetTTL(time.Minute)
+
+	w := httptest.NewRecorder()
+	s, err := store.Create(ctx, w)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Set(ctx, "cart", []int{1, 2}))
+	cookie := w.Result().Cookies()[0]
+
+	// 中间件从 cookie 读取会话
+	var got *Session
+	handler := SessionMiddleware(store)(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = SessionFromContext(r.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	handler(httptest.NewRecorder(), req)
+	assert.Equal(t, s.Id(), got.Id())
+	var cart []int
+	ok, err := got.Get("cart", &cart)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, cart)
+
+	// 签名错误
+	got = nil
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: s.Id() + ".bad"})
+	handler(httptest.NewRecorder(), req)
+	assert.Nil(t, got)
+
+	// 读取时延长过期时间
+	m.FastForward(40 * time.Second)
+	_, err = store.Load(ctx, s.Id())
+	assert.Nil(t, err)
+	m.FastForward(40 * time.Second)
+	_, err = store.Load(ctx, s.Id())
+	assert.Nil(t, err)
+
+	// 登录更换 id 保留数据
+	s2, err := store.Login(ctx, httptest.NewRecorder(), s, "u1")
+	assert.Nil(t, err)
+	assert.NotEqual(t, s.Id(), s2.Id())
+	assert.Equal(t, "u1", s2.UserId())
+	_, err = store.Load(ctx, s.Id())
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	s2, err = store.Load(ctx, s2.Id())
+	assert.Nil(t, err)
+	ok, _ = s2.Get("cart", &cart)
+	assert.True(t, ok)
+
+	// 保留字段不能写入
+	assert.ErrorIs(t, s2.Set(ctx, "_uid", "u2"), ErrSessionReservedKey)
+	assert.Equal(t, "u1", s2.UserId())
+
+	// 退出所有设备
+	s3, err := store.Login(ctx, httptest.NewRecorder(), nil, "u1")
+	assert.Nil(t, err)
+	assert.Nil(t, store.DestroyUser(ctx, "u1"))
+	// 正在处理的请求不会重新创建已销毁的会话
+	assert.ErrorIs(t, s2.Set(ctx, "cart", []int{3}), ErrSessionNotFound)
+	assert.False(t, m.Exists("sess:"+s2.Id()))
+	_, err = store.Load(ctx, s2.Id())
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = store.Load(ctx, s3.Id())
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	m.FastForward(2 * time.Minute)
+	w = httptest.NewRecorder()
+	s, _ = store.Create(ctx, w)
+	m.FastForward(2 * time.Minute)
+	_, err = store.Load(ctx, s.Id())
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -0,0 +1,316 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dawnco/cool/funcs"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// StreamHandler 处理一条消息, 返回 nil 时 ACK, 返回错误消息留在 pending 等待重新投递
+type StreamHandler func(ctx context.Context, msg redis.XMessage) error
+
+// StreamWorker redis stream 消费组处理器, 实现了 go-zero service.Service
+// 处理失败的消息超过 claimMinIdle 后被重新认领, 失败 maxFailures 次后转入死信 stream
+type StreamWorker struct {
+	client       redis.UniversalClient
+	group        string
+	consumer     string
+	handlers     map[string]StreamHandler
+	concurrency  int
+	maxFailures  int64
+	claimMinIdle time.Duration
+	block        time.Duration
+	deadSuffix   string
+	inflight     sync.Map     // stream:id => struct{} 正在处理的消息, 认领时跳过
+	claimMu      sync.RWMutex // 认领期间不能移除 inflight, 否则 ACK 之前认领到的消息会被再处理一次
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStreamWorker name 是 Init 的配置名称, group 是消费组名称
+// 消费者名称默认是 主机名-随机串
+func NewStreamWorker(name, group string) *StreamWorker {
+	host, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamWorker{
+		client:       Get(name),
+		group:        group,
+		consumer:     host + "-" + newToken()[:8],
+		handlers:     make(map[string]StreamHandler),
+		concurrency:  10,
+		maxFailures:  5,
+		claimMinIdle: time.Minute,
+		block:        2 * time.Second,
+		deadSuffix:   ":dead",
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Handle 注册 stream 的处理函数
+func (w *StreamWorker) Handle(stream string, handler StreamHandler) *StreamWorker {
+	w.handlers[stream] = handler
+	return w
+}
+
+func (w *StreamWorker) SetConsumer(consumer string) *StreamWorker {
+	w.consumer = consumer
+	return w
+}
+
+// SetConcurrency 每个 stream 同时处理的消息数
+func (w *StreamWorker) SetConcurrency(n int) *StreamWorker {
+	w.concurrency = n
+	return w
+}
+
+// SetMaxFailures 失败多少次后转入死信 stream
+func (w *StreamWorker) SetMaxFailures(n int64) *StreamWorker {
+	w.maxFailures = n
+	return w
+}
+
+// SetClaimMinIdle 消息 pending 超过多久被认为消费者已经挂了, 重新认领处理
+func (w *StreamWorker) SetClaimMinIdle(d time.Duration) *StreamWorker {
+	w.claimMinIdle = d
+	return w
+}
+
+// SetBlock 读取消息的阻塞时间, Stop 最多等待这么久
+func (w *StreamWorker) SetBlock(d time.Duration) *StreamWorker {
+	w.block = d
+	return w
+}
+
+// SetDeadLetterSuffix 死信 stream 的后缀 默认 :dead
+func (w *StreamWorker) SetDeadLetterSuffix(suffix string) *StreamWorker {
+	w.deadSuffix = suffix
+	return w
+}
+
+// Start 开始消费 阻塞直到 Stop
+func (w *StreamWorker) Start() {
+	for stream, handler := range w.handlers {
+		if err := w.createGroup(stream); err != nil {
+			logx.Errorf("stream %s 创建消费组 %s 失败 %s", stream, w.group, err.Error())
+		}
+
+		sem := make(chan struct{}, w.concurrency)
+		w.wg.Add(2)
+		go w.readLoop(stream, handler, sem)
+		go w.claimLoop(stream, handler, sem)
+	}
+	logx.Infof("stream worker %s 启动成功", w.consumer)
+
+	<-w.ctx.Done()
+}
+
+// Stop 停止消费, 等待处理中的消息完成
+func (w *StreamWorker) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *StreamWorker) createGroup(stream string) error {
+	err := w.client.XGroupCreateMkStream(w.ctx, stream, w.group, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func (w *StreamWorker) readLoop(stream string, handler StreamHandler, sem chan struct{}) {
+	defer w.wg.Done()
+
+	for w.ctx.Err() == nil {
+		streams, err := w.client.XReadGroup(w.ctx, &redis.XReadGroupArgs{
+			Group:    w.group,
+			Consumer: w.consumer,
+			Streams:  []string{stream, ">"},
+			Count:    int64(w.concurrency),
+			Block:    w.block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if w.ctx.Err() != nil {
+				return
+			}
+			logx.Errorf("stream %s 读取消息失败 %s", stream, err.Error())
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				_ = w.createGroup(stream)
+			}
+			w.sleep(time.Second)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				w.dispatch(stream, handler, sem, msg)
+			}
+		}
+	}
+}
+
+// claimLoop 定时认领其他消费者超时未处理的消息
+func (w *StreamWorker) claimLoop(stream string, handler StreamHandler, sem chan struct{}) {
+	defer w.wg.Done()
+
+	interval := max(w.claimMinIdle/2, 100*time.Millisecond)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.claim(stream, handler, sem)
+		}
+	}
+}
+
+func (w *StreamWorker) claim(stream string, handler StreamHandler, sem chan struct{}) {
+	start := "0-0"
+	for w.ctx.Err() == nil {
+		w.claimMu.RLock()
+		msgs, next, err := w.client.XAutoClaim(w.ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    w.group,
+			Consumer: w.consumer,
+			MinIdle:  w.claimMinIdle,
+			Start:    start,
+			Count:    int64(w.concurrency),
+		}).Result()
+		// 自己正在处理的消息超过 claimMinIdle 也会被认领, 不能重复处理
+		claimed := msgs[:0]
+		for _, msg := range msgs {
+			if _, ok := w.inflight.Load(stream + ":" + msg.ID); !ok {
+				claimed = append(claimed, msg)
+			}
+		}
+		w.claimMu.RUnlock()
+
+		if err != nil {
+			if w.ctx.Err() == nil {
+				logx.Errorf("stream %s 认领消息失败 %s", stream, err.Error())
+			}
+			return
+		}
+
+		for _, msg := range claimed {
+			if w.exceeded(stream, msg.ID) {
+				w.deadLetter(stream, msg)
+				continue
+			}
+			w.dispatch(stream, handler, sem, msg)
+		}
+
+		// 一页里的消息都被删除时 msgs 为空, 但是游标还没有结束
+		if next == "0-0" || next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+// exceeded 失败次数是否超过限制, 投递次数包含本次认领
+func (w *StreamWorker) exceeded(stream, id string) bool {
+	pending, err := w.client.XPendingExt(w.ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  w.group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return false
+	}
+	return pending[0].RetryCount-1 >= w.maxFailures
+}
+
+func (w *StreamWorker) deadLetter(stream string, msg redis.XMessage) {
+	values := make(map[string]any, len(msg.Values)+2)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	values["_stream"] = stream
+	values["_id"] = msg.ID
+
+	ctx := context.WithoutCancel(w.ctx)
+	if err := w.client.XAdd(ctx, &redis.XAddArgs{Stream: stream + w.deadSuffix, Values: values}).Err(); err != nil {
+		logx.Errorf("stream %s 消息 %s 转入死信失败 %s", stream, msg.ID, err.Error())
+		return
+	}
+	if err := w.client.XAck(ctx, stream, w.group, msg.ID).Err(); err != nil {
+		logx.Errorf("stream %s 消息 %s ACK 失败 %s", stream, msg.ID, err.Error())
+	}
+	logx.Errorf("stream %s 消息 %s 失败 %d 次 转入死信", stream, msg.ID, w.maxFailures)
+}
+
+func (w *StreamWorker) dispatch(stream string, handler StreamHandler, sem chan struct{}, msg redis.XMessage) {
+	key := stream + ":" + msg.ID
+	if _, loaded := w.inflight.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	select {
+	case sem <- struct{}{}:
+	case <-w.ctx.Done():
+		w.inflight.Delete(key)
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-sem }()
+		defer w.finish(key)
+
+		// 停止时让处理中的消息完成
+		ctx := context.WithoutCancel(w.ctx)
+		if err := w.handle(ctx, handler, msg); err != nil {
+			logx.Errorf("stream %s 处理消息 %s 失败 %s", stream, msg.ID, err.Error())
+			return
+		}
+		if err := w.client.XAck(ctx, stream, w.group, msg.ID).Err(); err != nil {
+			logx.Errorf("stream %s 消息 %s ACK 失败 %s", stream, msg.ID, err.Error())
+		}
+	}()
+}
+
+// finish 处理完成, 等待正在进行的认领结束后再移除
+func (w *StreamWorker) finish(key string) {
+	w.claimMu.Lock()
+	w.inflight.Delete(key)
+	w.claimMu.Unlock()
+}
+
+func (w *StreamWorker) handle(ctx context.Context, handler StreamHandler, msg redis.XMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic %v\n%s", r, funcs.StackTrace())
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+func (w *StreamWorker) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,142 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWorker(t *testing.T) {
+	setupRedis(t, "stream")
+	ctx := context.Background()
+	client := Get("stream")
+
+	var ok, failed int32
+	w := NewStreamWorker("stream", "g1").
+		SetBlock(50*time.Millisecond).
+		SetClaimMinIdle(50*time.Millisecond).
+		SetMaxFailures(2).
+		Handle("orders", func(ctx context.Context, msg redis.XMessage) error {
+			atomic.AddInt32(&ok, 1)
+			return nil
+		}).
+		Handle("pushes", func(ctx context.Context, msg redis.XMessage) error {
+			if atomic.AddInt32(&failed, 1) == 1 {
+				panic("boom")
+			}
+			return errors.New("always fail")
+		})
+
+	go w.Start()
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "orders", Values: map[string]any{"i": i}}).Err())
+	}
+	assert.Nil(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "pushes", Values: map[string]any{"uid": "7"}}).Err())
+
+	assert.Eventually(t, func() bool {
+		n, _ := client.XLen(ctx, "pushes:dead").Result()
+		return n == 1 && atomic.LoadInt32(&ok) == 5
+	}, 5*time.Second, 20*time.Millisecond)
+
+	w.Stop()
+
+	// 成功和死信的消息都已经 ACK
+	for _, stream := range []string{"orders", "pushes"} {
+		pending, err := client.XPending(ctx, stream, "g1").Result()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), pending.Count)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failed))
+
+	dead, err := client.XRange(ctx, "pushes:dead", "-", "+").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "7", dead[0].Values["uid"])
+	assert.Equal(t, "pushes", dead[0].Values["_stream"])
+}
+
+func TestStreamWorkerSkipInflight(t *testing.T) {
+	setupRedis(t, "stream")
+	ctx := context.Background()
+	client := Get("stream")
+
+	// 处理时间超过 claimMinIdle, 不能被自己重新认领再处理一次
+	var calls int32
+	w := NewStreamWorker("stream", "g1").
+		SetBlock(50*time.Millisecond).
+		SetClaimMinIdle(50*time.Millisecond).
+		Handle("slow", func(ctx context.Context, msg redis.XMessage) error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(400 * time.Millisecond)
+			return nil
+		})
+
+	go w.Start()
+	assert.Nil(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "slow", Values: map[string]any{"i": 1}}).Err())
+
+	assert.Eventually(t, func() bool {
+		pending, _ := client.XPending(ctx, "slow", "g1").Result()
+		return atomic.LoadInt32(&calls) == 1 && pending != nil && pending.Count == 0
+	}, 3*time.Second, 20*time.Millisecond)
+	w.Stop()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// emptyPageHook 第一次 XAUTOCLAIM 返回空的一页和没有结束的游标, 模拟一页的消息都已经被删除
+type emptyPageHook struct {
+	cursor string
+	done   int32
+}
+
+func (h *emptyPageHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *emptyPageHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if c, ok := cmd.(*redis.XAutoClaimCmd); ok && atomic.CompareAndSwapInt32(&h.done, 0, 1) {
+			c.SetVal(nil, h.cursor)
+			return nil
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *emptyPageHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestStreamWorkerClaimCursor(t *testing.T) {
+	setupRedis(t, "stream")
+	ctx := context.Background()
+	client := Get("stream")
+
+	// 其他消费者读取后挂了
+	assert.Nil(t, client.XGroupCreateMkStream(ctx, "c", "g1", "0").Err())
+	var ids []string
+	for i := 0; i < 2; i++ {
+		id, err := client.XAdd(ctx, &redis.XAddArgs{Stream: "c", Values: map[string]any{"i": i}}).Result()
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	assert.Nil(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g1", Consumer: "dead", Streams: []string{"c", ">"}}).Err())
+	client.AddHook(&emptyPageHook{cursor: ids[0]})
+
+	var calls int32
+	w := NewStreamWorker("stream", "g1").
+		SetClaimMinIdle(time.Millisecond).
+		Handle("c", func(ctx context.Context, msg redis.XMessage) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})
+	time.Sleep(5 * time.Millisecond)
+	w.claim("c", w.handlers["c"], make(chan struct{}, 2))
+	w.Stop()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,209 @@
+package wredis
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/dawnco/cool/object_cache"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+// CacheStats 命中统计
+type CacheStats struct {
+	L1Hits int64
+	L2Hits int64
+	Misses int64
+}
+
+// HitRate 总命中率 (L1 + L2) / 总次数
+func (s CacheStats) HitRate() float64 {
+	total := s.L1Hits + s.L2Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.L1Hits+s.L2Hits) / float64(total)
+}
+
+type l1Entry[T any] struct {
+	value    T
+	expireAt time.Time
+}
+
+type invalidateEvent struct {
+	Origin string
+	Keys   []string
+}
+
+// TwoLevelCache 二级缓存, L1 是进程内 object_cache, L2 是 redis
+// L2 命中时回填 L1, 写入和删除通过 pub/sub 通知其他实例删除 L1
+type TwoLevelCache[T any] struct {
+	l1    *object_cache.Cache[l1Entry[T]]
+	l1TTL time.Duration
+	l2    *TypedCache[T]
+	l2TTL time.Duration
+	bus   *EventBus
+	topic string
+	// 实例标识, 忽略自己发出的通知
+	origin string
+	group  syncx.SingleFlight
+
+	l1Hits atomic.Int64
+	l2Hits atomic.Int64
+	misses atomic.Int64
+}
+
+// NewTwoLevelCache name 是 Init 的配置名称, prefix 是 redis key 前缀, 也用作通知的 topic
+// l1TTL 是进程内缓存时间, 应该比较短, 通知丢失时最多读到 l1TTL 的旧数据
+func NewTwoLevelCache[T any](name, prefix string, l1TTL, l2TTL time.Duration) *TwoLevelCache[T] {
+	c := &TwoLevelCache[T]{
+		l1:     object_cache.NewCache[l1Entry[T]](max(int(l1TTL/time.Second), 1), 1),
+		l1TTL:  l1TTL,
+		l2:     NewTypedCache[T](name, prefix),
+		l2TTL:  l2TTL,
+		bus:    NewEventBus(name),
+		topic:  "cache:invalidate:" + prefix,
+		origin: newToken(),
+		group:  syncx.NewSingleFlight(),
+	}
+	if err := Subscribe(c.bus, c.topic, c.onInvalidate); err != nil {
+		logx.Errorf("二级缓存 %s 订阅失败 %s", prefix, err.Error())
+	}
+	return c
+}
+
+// SetCodec 设置 L2 的编码
+func (c *TwoLevelCache[T]) SetCodec(codec Codec) *TwoLevelCache[T] {
+	c.l2.SetCodec(codec)
+	return c
+}
+
+// Get 获取缓存, 不存在时第二个返回值为 false
+func (c *TwoLevelCache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	if v, ok := c.getL1(key); ok {
+		c.l1Hits.Add(1)
+		return v, true, nil
+	}
+
+	v, ok, err := c.l2.Get(ctx, key)
+	if err != nil {
+		return v, false, err
+	}
+	if !ok {
+		c.misses.Add(1)
+		return v, false, nil
+	}
+	c.l2Hits.Add(1)
+	c.setL1(key, v)
+	return v, true, nil
+}
+
+// Set 写入两级缓存, 并通知其他实例删除 L1
+func (c *TwoLevelCache[T]) Set(ctx context.Context, key string, v T) error {
+	if err := c.l2.Set(ctx, key, v, c.l2TTL); err != nil {
+		return err
+	}
+	c.setL1(key, v)
+	c.publish(ctx, key)
+	return nil
+}
+
+// Delete 删除两级缓存, 并通知其他实例删除 L1
+func (c *TwoLevelCache[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	for _, key := range keys {
+		c.l1.Delete(key)
+	}
+	if err := c.l2.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	c.publish(ctx, keys...)
+	return nil
+}
+
+// GetOrLoad 获取缓存, 两级都不存在时调用 loader 加载并写入缓存
+// 同一个 key 并发调用时 loader 只执行一次
+func (c *TwoLevelCache[T]) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) (T, error)) (T, error) {
+	v, ok, err := c.Get(ctx, key)
+	if err != nil {
+		logx.WithContext(ctx).Errorf("读取缓存 %s 失败 %s", key, err.Error())
+	}
+	if ok {
+		return v, nil
+	}
+
+	val, err := c.group.Do(key, func() (any, error) {
+		// 等待的调用共用这次加载, 不受第一个调用方取消的影响
+		ctx := context.WithoutCancel(ctx)
+		if v, ok, err := c.l2.Get(ctx, key); err == nil && ok {
+			c.setL1(key, v)
+			return v, nil
+		}
+
+		v, err := loader(ctx)
+		if err != nil {
+			return v, err
+		}
+		if err := c.Set(ctx, key, v); err != nil {
+			logx.WithContext(ctx).Errorf("写入缓存 %s 失败 %s", key, err.Error())
+		}
+		return v, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	// T 为接口类型时 loader 可能返回 nil, 直接断言会 panic
+	v, _ = val.(T)
+	return v, nil
+}
+
+// Stats 命中统计
+func (c *TwoLevelCache[T]) Stats() CacheStats {
+	return CacheStats{
+		L1Hits: c.l1Hits.Load(),
+		L2Hits: c.l2Hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// Close 取消订阅, 停止 L1 清理并清空 L1, L2 的数据不受影响
+func (c *TwoLevelCache[T]) Close() error {
+	c.l1.Close()
+	return c.bus.Close()
+}
+
+func (c *TwoLevelCache[T]) getL1(key string) (T, bool) {
+	entry, ok := c.l1.Get(key)
+	if !ok || time.Now().After(entry.expireAt) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *TwoLevelCache[T]) setL1(key string, v T) {
+	if c.l1TTL <= 0 {
+		return
+	}
+	c.l1.Set(key, l1Entry[T]{value: v, expireAt: time.Now().Add(c.l1TTL)})
+}
+
+func (c *TwoLevelCache[T]) publish(ctx context.Context, keys ...string) {
+	err := c.bus.Publish(ctx, c.topic, invalidateEvent{Origin: c.origin, Keys: keys})
+	if err != nil {
+		logx.WithContext(ctx).Errorf("二级缓存 %s 发送失效通知失败 %s", c.topic, err.Error())
+	}
+}
+
+func (c *TwoLevelCache[T]) onInvalidate(ctx context.Context, e invalidateEvent) {
+	if e.Origin == c.origin {
+		return
+	}
+	for _, key := range e.Keys {
+		c.l1.Delete(key)
+	}
+}
@@ -0,0 +1,101 @@
+package wredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoLevelCache(t *testing.T) {
+	setupRedis(t, "tlc")
+	ctx := context.Background()
+
+	a := NewTwoLevelCache[string]("tlc", "conf", time.Minute, time.Hour)
+	b := NewTwoLevelCache[string]("tlc", "conf", time.Minute, time.Hour)
+	defer a.Close()
+	defer b.Close()
+
+	_, ok, err := b.Get(ctx, "k")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, a.Set(ctx, "k", "v1"))
+
+	// 第一次 L2 命中 回填 L1
+	v, ok, err := b.Get(ctx, "k")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v1", v)
+	v, _, _ = b.Get(ctx, "k")
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, CacheStats{L1Hits: 1, L2Hits: 1, Misses: 1}, b.Stats())
+	assert.InDelta(t, 2.0/3, b.Stats().HitRate(), 0.001)
+
+	// 其他实例写入后 L1 失效
+	assert.Eventually(t, func() bool {
+		assert.Nil(t, a.Set(ctx, "k", "v2"))
+		v, _, _ := b.Get(ctx, "k")
+		return v == "v2"
+	}, 2*time.Second, 20*time.Millisecond)
+
+	assert.Nil(t, a.Delete(ctx, "k"))
+	assert.Eventually(t, func() bool {
+		_, ok, _ := b.Get(ctx, "k")
+		return !ok
+	}, 2*time.Second, 20*time.Millisecond)
+
+	calls := 0
+	loader := func(ctx context.Context) (string, error) {
+		calls++
+		return "loaded", nil
+	}
+	v, err = a.GetOrLoad(ctx, "load", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, "loaded", v)
+	v, _ = a.GetOrLoad(ctx, "load", loader)
+	assert.Equal(t, "loaded", v)
+	assert.Equal(t, 1, calls)
+}
+
+func TestTwoLevelCacheLoadNil(t *testing.T) {
+	setupRedis(t, "tlc")
+	ctx := context.Background()
+
+	c := NewTwoLevelCache[any]("tlc", "any", time.Minute, time.Hour)
+	defer c.Close()
+
+	// loader 返回 nil 接口不 panic
+	v, err := c.GetOrLoad(ctx, "nil", func(ctx context.Context) (any, error) {
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	// loader 不使用调用方已取消的 ctx
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	v, err = c.GetOrLoad(canceled, "canceled", func(ctx context.Context) (any, error) {
+		return "v", ctx.Err()
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+}
+
+func TestTwoLevelCacheL1Expire(t *testing.T) {
+	setupRedis(t, "tlc")
+	ctx := context.Background()
+
+	c := NewTwoLevelCache[int]("tlc", "num", 50*time.Millisecond, time.Hour)
+	defer c.Close()
+
+	assert.Nil(t, c.Set(ctx, "n", 1))
+	_, _, _ = c.Get(ctx, "n")
+	time.Sleep(80 * time.Millisecond)
+	v, ok, err := c.Get(ctx, "n")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, CacheStats{L1Hits: 1, L2Hits: 1}, c.Stats())
+}
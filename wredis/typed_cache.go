@@ -0,0 +1,187 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+// ErrNotExist 布隆过滤器判断 key 不存在
+var ErrNotExist = errors.New("key not exist")
+
+// TypedCache 类型化的缓存 值按 codec 编码后存入 redis
+type TypedCache[T any] struct {
+	client redis.UniversalClient
+	prefix string
+	codec  Codec
+	jitter float64
+	group  syncx.SingleFlight
+	bloom  *BloomFilter
+}
+
+// NewTypedCache name 是 Init 的配置名称, prefix 是 redis key 前缀
+// 默认使用 json 编码, 过期时间随机增加 10% 防止缓存雪崩
+func NewTypedCache[T any](name, prefix string) *TypedCache[T] {
+	return &TypedCache[T]{
+		client: Get(name),
+		prefix: prefix,
+		codec:  JsonCodec{},
+		jitter: 0.1,
+		group:  syncx.NewSingleFlight(),
+	}
+}
+
+func (c *TypedCache[T]) SetCodec(codec Codec) *TypedCache[T] {
+	c.codec = codec
+	return c
+}
+
+// SetJitter 过期时间随机增加 [0, ttl*jitter), 0 不增加
+func (c *TypedCache[T]) SetJitter(jitter float64) *TypedCache[T] {
+	c.jitter = jitter
+	return c
+}
+
+// SetBloom GetOrLoad 先检查布隆过滤器, 不存在的 key 直接返回 ErrNotExist, 防止缓存穿透
+// 新增数据时需要把 key 加入过滤器
+func (c *TypedCache[T]) SetBloom(bloom *BloomFilter) *TypedCache[T] {
+	c.bloom = bloom
+	return c
+}
+
+// Get 获取缓存, 不存在时第二个返回值为 false
+func (c *TypedCache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var v T
+	data, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, err
+	}
+	if err := c.codec.Unmarshal(data, &v); err != nil {
+		return v, false, err
+	}
+	return v, true, nil
+}
+
+// Set 设置缓存 ttl 为 0 不过期
+func (c *TypedCache[T]) Set(ctx context.Context, key string, v T, ttl time.Duration) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.key(key), data, c.ttl(ttl)).Err()
+}
+
+// GetOrLoad 获取缓存, 不存在时调用 loader 加载并写入缓存
+// 同一个 key 并发调用时 loader 只执行一次
+func (c *TypedCache[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	if c.bloom != nil {
+		exists, err := c.bloom.Exists(ctx, key)
+		if err != nil {
+			logx.WithContext(ctx).Errorf("布隆过滤器检查 %s 失败 %s", key, err.Error())
+		} else if !exists {
+			var zero T
+			return zero, ErrNotExist
+		}
+	}
+
+	v, ok, err := c.Get(ctx, key)
+	if err != nil {
+		logx.WithContext(ctx).Errorf("读取缓存 %s 失败 %s", key, err.Error())
+	}
+	if ok {
+		return v, nil
+	}
+
+	val, err := c.group.Do(key, func() (any, error) {
+		// 等待的调用共用这次加载, 不受第一个调用方取消的影响
+		ctx := context.WithoutCancel(ctx)
+		// 其他调用可能已经加载完成
+		if v, ok, err := c.Get(ctx, key); err == nil && ok {
+			return v, nil
+		}
+
+		v, err := loader(ctx)
+		if err != nil {
+			return v, err
+		}
+		if err := c.Set(ctx, key, v, ttl); err != nil {
+			logx.WithContext(ctx).Errorf("写入缓存 %s 失败 %s", key, err.Error())
+		}
+		return v, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	// T 为接口类型时 loader 可能返回 nil, 直接断言会 panic
+	v, _ = val.(T)
+	return v, nil
+}
+
+// MGet 批量获取, 返回存在的 key => 值
+// 使用 pipeline 逐个 GET, cluster 模式下 key 可以在不同 slot
+func (c *TypedCache[T]) MGet(ctx context.Context, keys ...string) (map[string]T, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, c.key(key))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	result := make(map[string]T, len(keys))
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var v T
+		if err := c.codec.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		result[keys[i]] = v
+	}
+	return result, nil
+}
+
+// Delete 删除缓存
+func (c *TypedCache[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, c.key(key))
+		}
+		return nil
+	})
+	return err
+}
+
+func (c *TypedCache[T]) key(key string) string {
+	if c.prefix == "" {
+		return key
+	}
+	return c.prefix + ":" + key
+}
+
+func (c *TypedCache[T]) ttl(ttl time.Duration) time.Duration {
+	if ttl <= 0 || c.jitter <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*c.jitter*float64(ttl))
+}
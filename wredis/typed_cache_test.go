@@ -0,0 +1,115 @@
+package wredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheUser struct {
+	Id   int64
+	Name string
+}
+
+func TestTypedCache(t *testing.T) {
+	m := setupRedis(t, "cache")
+	ctx := context.Background()
+
+	c := NewTypedCache[cacheUser]("cache", "user").SetJitter(0)
+
+	_, ok, err := c.Get(ctx, "1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, c.Set(ctx, "1", cacheUser{Id: 1, Name: "a"}, time.Minute))
+	assert.Equal(t, time.Minute, m.TTL("user:1"))
+
+	v, ok, err := c.Get(ctx, "1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", v.Name)
+
+	assert.Nil(t, c.Set(ctx, "2", cacheUser{Id: 2, Name: "b"}, time.Minute))
+	vals, err := c.MGet(ctx, "1", "2", "3")
+	assert.Nil(t, err)
+	assert.Len(t, vals, 2)
+	assert.Equal(t, "b", vals["2"].Name)
+
+	assert.Nil(t, c.Delete(ctx, "1", "2"))
+	assert.False(t, m.Exists("user:1"))
+	assert.False(t, m.Exists("user:2"))
+}
+
+func TestTypedCacheGetOrLoad(t *testing.T) {
+	setupRedis(t, "cache")
+	ctx := context.Background()
+
+	c := NewTypedCache[cacheUser]("cache", "user")
+
+	var calls int32
+	loader := func(ctx context.Context) (cacheUser, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return cacheUser{Id: 9, Name: "loaded"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(ctx, "9", time.Minute, loader)
+			assert.Nil(t, err)
+			assert.Equal(t, "loaded", v.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err := c.GetOrLoad(ctx, "10", time.Minute, func(ctx context.Context) (cacheUser, error) {
+		return cacheUser{}, errors.New("load failed")
+	})
+	assert.EqualError(t, err, "load failed")
+
+	// loader 返回 nil 接口不 panic
+	anyCache := NewTypedCache[any]("cache", "any")
+	v, err := anyCache.GetOrLoad(ctx, "1", time.Minute, func(ctx context.Context) (any, error) {
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	// loader 不使用调用方已取消的 ctx
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.GetOrLoad(canceled, "11", time.Minute, func(ctx context.Context) (cacheUser, error) {
+		return cacheUser{Id: 11}, ctx.Err()
+	})
+	assert.Nil(t, err)
+}
+
+func TestTypedCacheCodec(t *testing.T) {
+	setupRedis(t, "cache")
+	ctx := context.Background()
+
+	codecs := []Codec{
+		MsgpackCodec{},
+		GzipCodec{Codec: JsonCodec{}, MinSize: 64},
+	}
+	for _, codec := range codecs {
+		c := NewTypedCache[cacheUser]("cache", "codec").SetCodec(codec)
+		for _, name := range []string{"short", strings.Repeat("long", 100)} {
+			assert.Nil(t, c.Set(ctx, "1", cacheUser{Id: 1, Name: name}, time.Minute))
+			v, ok, err := c.Get(ctx, "1")
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, name, v.Name)
+		}
+	}
+}